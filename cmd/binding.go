@@ -17,121 +17,411 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/automationbroker/apb/pkg/binding"
 	"github.com/automationbroker/apb/pkg/util"
-	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/clients"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 var bindingNamespace string
+var bindingInject bool
+var bindingEnvPrefix string
+var bindingMountPath string
+var bindingKeyMap []string
+var bindingKeyPrefix string
+var bindingOnly []string
+var bindingFormat string
+var bindingTargetNamespace string
+var bindingOwnerLabels bool
+var bindingSync bool
 
 var bindingCmd = &cobra.Command{
 	Use:   "binding",
 	Short: "Manage bindings",
-	Long:  `Create bindings`,
+	Long:  `Create, list and remove bindings`,
 }
 
 var bindingAddCmd = &cobra.Command{
-	Use:   "add <secret-name> <deployment-config-name>",
+	Use:   "add <secret-name> <workload>",
 	Short: "Add bind credentials to an application",
-	Long:  `Add bind credentials created by an APB to another application's deployment config`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Add bind credentials created by an APB to another application's workload.
+The workload is given as <kind>/<name> (dc, deployment, statefulset or cronjob); a bare name refers to a deployment config`,
+	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		addBinding(args)
 	},
 }
 
+var bindingListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List bind credentials created by apb",
+	Long:  `List the bind credential secrets created by 'apb binding add', their source APB secret and the workloads consuming them`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listBindings()
+	},
+}
+
+var bindingRemoveCmd = &cobra.Command{
+	Use:   "remove <binding-secret-name>",
+	Short: "Remove bind credentials from applications",
+	Long:  `Remove bind credentials injected by 'apb binding add --inject' from all workloads and delete the binding secret`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		removeBinding(args[0])
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(bindingCmd)
+	bindingCmd.PersistentFlags().StringVarP(&bindingNamespace, "namespace", "n", "", "Namespace of binding")
+
 	// Binding Add Flags
-	bindingAddCmd.Flags().StringVarP(&bindingNamespace, "namespace", "n", "", "Namespace of binding")
+	bindingAddCmd.Flags().BoolVar(&bindingInject, "inject", false, "Patch the workload's pod template to consume the binding secret")
+	bindingAddCmd.Flags().StringVar(&bindingEnvPrefix, "env-prefix", "", "Prefix for the environment variables injected from the binding secret")
+	bindingAddCmd.Flags().StringVar(&bindingMountPath, "mount-path", "", "Mount the binding secret as a volume at this path instead of injecting environment variables")
+	bindingAddCmd.Flags().StringSliceVar(&bindingKeyMap, "map", []string{}, "Rename a credential in the binding secret, as src=DEST. May be repeated")
+	bindingAddCmd.Flags().StringVar(&bindingKeyPrefix, "prefix", "", "Prefix for the keys of the binding secret, unlike --env-prefix which only prefixes injected environment variables")
+	bindingAddCmd.Flags().StringSliceVar(&bindingOnly, "only", []string{}, "Comma separated list of credentials to include in the binding secret")
+	bindingAddCmd.Flags().StringVar(&bindingFormat, "format", binding.FormatEnv, "Layout of the binding secret: env, file or json")
+	bindingAddCmd.Flags().StringVar(&bindingTargetNamespace, "target-namespace", "", "Namespace of the application, when different from the namespace of the APB secret")
+	bindingAddCmd.Flags().BoolVar(&bindingOwnerLabels, "owner-labels", false, "Label the binding secret with the namespace and name of the APB secret it was created from")
+	bindingAddCmd.Flags().BoolVar(&bindingSync, "sync", false, "Keep running and copy the binding secret to the target namespace again whenever the APB secret changes")
 
 	bindingCmd.AddCommand(bindingAddCmd)
+	bindingCmd.AddCommand(bindingListCmd)
+	bindingCmd.AddCommand(bindingRemoveCmd)
 }
 
-func addBinding(args []string) {
+// Default bindingNamespace to the current namespace, returning false if it can't be determined
+func resolveBindingNamespace() bool {
 	if bindingNamespace == "" {
 		bindingNamespace = util.GetCurrentNamespace(kubeConfig)
 		if bindingNamespace == "" {
 			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
-			return
+			return false
 		}
 	}
+	return true
+}
+
+func addBinding(args []string) {
+	if !resolveBindingNamespace() {
+		return
+	}
 	secretName := args[0]
 	newSecretName := fmt.Sprintf("%v-creds", secretName)
 	appName := args[1]
 	log.Debug(secretName)
 	log.Debug(appName)
+	workload, err := binding.ParseWorkload(appName)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if bindingEnvPrefix != "" && bindingMountPath != "" {
+		log.Errorf("--env-prefix and --mount-path can not be used together")
+		return
+	}
+	appNamespace := bindingNamespace
+	if bindingTargetNamespace != "" {
+		appNamespace = bindingTargetNamespace
+	}
+	if bindingSync && appNamespace == bindingNamespace {
+		log.Errorf("--sync requires a --target-namespace different from the binding namespace")
+		return
+	}
+	if appNamespace != bindingNamespace && !checkBindingAccess(workload, appNamespace) {
+		return
+	}
 	log.Infof("Create a binding using secret [%s] to app [%s]\n", secretName, appName)
-	secretData, err := extractCredentialsAsSecret(secretName, bindingNamespace)
+	s, err := buildBindingSecret(secretName, newSecretName)
 	if err != nil {
-		log.Errorf("Unable to retrieve secret data from secret: [%v]", err)
+		log.Error(err)
 		return
 	}
-	extCreds, err := buildExtractedCredentials(secretData)
+
+	// Re-running updates the secret of an earlier binding
+	err = binding.SaveSecret(bindingNamespace, s)
 	if err != nil {
-		log.Errorf("Unexpected error building extracted creds: [%v]", err)
+		log.Errorf("Unable to save secret [%v] in namespace [%v]: %v", newSecretName, bindingNamespace, err)
 		return
 	}
-	data := map[string][]byte{}
-	for key, value := range extCreds.Credentials {
-		d, err := json.Marshal(value)
+	fmt.Printf("Successfully saved secret [%v] in namespace [%v].\n", newSecretName, bindingNamespace)
+
+	if appNamespace != bindingNamespace {
+		err = binding.CopyToNamespace(newSecretName, bindingNamespace, appNamespace)
 		if err != nil {
-			log.Errorf("error marshalling extracted credential data: %v", err)
+			log.Errorf("Unable to copy secret [%v] to namespace [%v]: %v", newSecretName, appNamespace, err)
 			return
 		}
-		data[key] = d
+		fmt.Printf("Successfully copied secret [%v] to namespace [%v].\n", newSecretName, appNamespace)
 	}
-	s := &apiv1.Secret{
+
+	if !bindingInject {
+		namespaceArg := ""
+		if appNamespace != bindingNamespace {
+			namespaceArg = fmt.Sprintf(" -n %v", appNamespace)
+		}
+		fmt.Printf("Use the following command to attach the binding to your application:\n")
+		if bindingMountPath != "" {
+			fmt.Printf("oc set volume %v%v --add --secret-name=%v --mount-path=%v\n", workload, namespaceArg, newSecretName, bindingMountPath)
+		} else if bindingEnvPrefix != "" {
+			fmt.Printf("oc set env %v%v --from=secret/%v --prefix=%v\n", workload, namespaceArg, newSecretName, bindingEnvPrefix)
+		} else {
+			fmt.Printf("oc set env %v%v --from=secret/%v\n", workload, namespaceArg, newSecretName)
+		}
+	} else {
+		injection := binding.Injection{
+			Secret:    newSecretName,
+			EnvPrefix: bindingEnvPrefix,
+			MountPath: bindingMountPath,
+		}
+		err = binding.Inject(appNamespace, workload, injection)
+		if err != nil {
+			log.Errorf("Unable to inject secret [%v] into [%v]: %v", newSecretName, workload, err)
+			return
+		}
+		fmt.Printf("Successfully injected secret [%v] into [%v].\n", newSecretName, workload)
+	}
+
+	if bindingSync {
+		syncBinding(secretName, newSecretName, appNamespace)
+	}
+	return
+}
+
+// Build the binding secret holding the credentials extracted by an APB into its secret
+func buildBindingSecret(secretName string, newSecretName string) (*apiv1.Secret, error) {
+	creds, err := binding.ExtractCredentials(secretName, bindingNamespace)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := binding.ParseMapping(bindingKeyMap)
+	if err != nil {
+		return nil, err
+	}
+	opts := binding.CredentialOptions{
+		Only:    bindingOnly,
+		Mapping: mapping,
+		Prefix:  bindingKeyPrefix,
+		Format:  bindingFormat,
+	}
+	data, err := binding.BuildSecretData(creds, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to build binding secret data: %v", err)
+	}
+	labels := map[string]string{
+		binding.BindingLabel: "true",
+	}
+	if bindingOwnerLabels {
+		labels[binding.SourceNamespaceLabel] = bindingNamespace
+		labels[binding.SourceSecretLabel] = secretName
+	}
+	return &apiv1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: newSecretName,
+			Name:   newSecretName,
+			Labels: labels,
+			Annotations: map[string]string{
+				binding.SourceAnnotation: secretName,
+			},
 		},
 		Data: data,
+	}, nil
+}
+
+// Check the current user may read the source secret, save the binding secret and its copy, and patch
+// the workload in the target namespace. Saving updates the secrets left by an earlier run.
+func checkBindingAccess(workload binding.Workload, targetNamespace string) bool {
+	checks := []util.ResourceAccess{
+		{Namespace: bindingNamespace, Verb: "get", Resource: "secrets"},
+		{Namespace: bindingNamespace, Verb: "create", Resource: "secrets"},
+		{Namespace: bindingNamespace, Verb: "update", Resource: "secrets"},
+		{Namespace: targetNamespace, Verb: "create", Resource: "secrets"},
+		{Namespace: targetNamespace, Verb: "update", Resource: "secrets"},
+	}
+	if bindingInject {
+		checks = append(checks, util.ResourceAccess{Namespace: targetNamespace, Verb: "update", Group: workload.APIGroup(), Resource: workload.Resource()})
+	}
+	allowed := true
+	for _, check := range checks {
+		ok, err := util.CanI(check)
+		if err != nil {
+			log.Errorf("Unable to check access to %v in namespace [%v]: %v", check.Resource, check.Namespace, err)
+			return false
+		}
+		if !ok {
+			log.Errorf("Not allowed to %v %v in namespace [%v]", check.Verb, check.Resource, check.Namespace)
+			allowed = false
+		}
 	}
+	return allowed
+}
 
+// Watch the APB secret and copy the binding secret again whenever it changes, until interrupted
+func syncBinding(secretName string, newSecretName string, targetNamespace string) {
 	k8scli, err := clients.Kubernetes()
 	if err != nil {
-		panic(err.Error())
-	}
-	_, err = k8scli.Client.CoreV1().Secrets(bindingNamespace).Create(s)
-	if err != nil {
-		log.Errorf("Unable to create secret [%v] in namespace [%v]", newSecretName, bindingNamespace)
+		log.Errorf("Unable to retrieve kubernetes client - %v", err)
 		return
 	}
-
-	fmt.Printf("Successfully created secret [%v] in namespace [%v].\n", newSecretName, bindingNamespace)
-	fmt.Printf("Use the following command to attach the binding to your application:\n")
-	fmt.Printf("oc set env dc/%v --from=secret/%v\n", appName, newSecretName)
-	return
-
+	secrets := k8scli.Client.CoreV1().Secrets(bindingNamespace)
+	fmt.Printf("Syncing secret [%v] to namespace [%v] when [%v] changes. Press Ctrl-C to stop.\n", newSecretName, targetNamespace, secretName)
+	resourceVersion := ""
+	for {
+		if resourceVersion == "" {
+			source, err := secrets.Get(secretName, metav1.GetOptions{})
+			if err != nil {
+				log.Errorf("Unable to get secret [%v]: %v", secretName, err)
+				return
+			}
+			resourceVersion = source.ResourceVersion
+		}
+		watcher, err := secrets.Watch(metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", secretName).String(),
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			log.Errorf("Unable to watch secret [%v]: %v", secretName, err)
+			return
+		}
+		for event := range watcher.ResultChan() {
+			switch event.Type {
+			case watch.Modified:
+				source, ok := event.Object.(*apiv1.Secret)
+				if !ok {
+					continue
+				}
+				resourceVersion = source.ResourceVersion
+				err = resyncBinding(secretName, newSecretName, targetNamespace)
+				if err != nil {
+					log.Errorf("Unable to sync secret [%v]: %v", newSecretName, err)
+					continue
+				}
+				fmt.Printf("Secret [%v] changed, synced [%v] to namespace [%v]\n", secretName, newSecretName, targetNamespace)
+			case watch.Deleted:
+				log.Warnf("Secret [%v] was deleted, stopping sync", secretName)
+				watcher.Stop()
+				return
+			case watch.Error:
+				// The resource version is likely too old, start over from the current one
+				log.Debugf("Watch error on secret [%v]: %v", secretName, event.Object)
+				resourceVersion = ""
+			}
+		}
+		log.Debugf("Watch on secret [%v] closed, reconnecting", secretName)
+	}
 }
 
-// ExtractCredentialsAsSecret - Extract credentials from APB as secret in namespace.
-func extractCredentialsAsSecret(podname string, namespace string) ([]byte, error) {
-	k8s, err := clients.Kubernetes()
+// Rebuild the binding secret from the APB secret and copy it to the target namespace again
+func resyncBinding(secretName string, newSecretName string, targetNamespace string) error {
+	k8scli, err := clients.Kubernetes()
 	if err != nil {
-		return nil, fmt.Errorf("Unable to retrieve kubernetes client - [%v]", err)
+		return err
 	}
+	s, err := buildBindingSecret(secretName, newSecretName)
+	if err != nil {
+		return err
+	}
+	existing, err := k8scli.Client.CoreV1().Secrets(bindingNamespace).Get(newSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Data = s.Data
+	_, err = k8scli.Client.CoreV1().Secrets(bindingNamespace).Update(existing)
+	if err != nil {
+		return err
+	}
+	return binding.CopyToNamespace(newSecretName, bindingNamespace, targetNamespace)
+}
 
-	secret, err := k8s.GetSecretData(podname, namespace)
+func listBindings() {
+	if !resolveBindingNamespace() {
+		return
+	}
+	k8scli, err := clients.Kubernetes()
 	if err != nil {
-		return nil, fmt.Errorf("Unable to retrieve secret [%v] - [%v]", podname, err)
+		log.Errorf("Unable to retrieve kubernetes client - %v", err)
+		return
+	}
+	secrets, err := k8scli.Client.CoreV1().Secrets(bindingNamespace).List(metav1.ListOptions{LabelSelector: binding.BindingLabel})
+	if err != nil {
+		log.Errorf("Unable to list binding secrets in namespace [%v]: %v", bindingNamespace, err)
+		return
+	}
+	if len(secrets.Items) == 0 {
+		fmt.Printf("No bindings found in namespace [%v]\n", bindingNamespace)
+		return
+	}
+	consumers, err := binding.FindConsumers(bindingNamespace)
+	if err != nil {
+		log.Errorf("Unable to find workloads consuming bindings in namespace [%v]: %v", bindingNamespace, err)
+		return
 	}
 
-	return secret["fields"], nil
+	colName := &util.TableColumn{Header: "NAME"}
+	colSource := &util.TableColumn{Header: "SOURCE"}
+	colConsumers := &util.TableColumn{Header: "CONSUMERS"}
+	for _, s := range secrets.Items {
+		colName.Data = append(colName.Data, s.Name)
+		colSource.Data = append(colSource.Data, s.Annotations[binding.SourceAnnotation])
+		names := []string{}
+		for _, c := range consumers[s.Name] {
+			names = append(names, c.Workload.String())
+		}
+		colConsumers.Data = append(colConsumers.Data, strings.Join(names, ", "))
+	}
+	tableToPrint := []*util.TableColumn{colName, colSource, colConsumers}
+	util.PrintTable(tableToPrint)
 }
 
-func buildExtractedCredentials(output []byte) (*bundle.ExtractedCredentials, error) {
-	creds := make(map[string]interface{})
-	err := json.Unmarshal(output, &creds)
+func removeBinding(secretName string) {
+	if !resolveBindingNamespace() {
+		return
+	}
+	k8scli, err := clients.Kubernetes()
 	if err != nil {
-		return nil, err
+		log.Errorf("Unable to retrieve kubernetes client - %v", err)
+		return
+	}
+	secret, err := k8scli.Client.CoreV1().Secrets(bindingNamespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("Unable to get binding secret [%v] in namespace [%v]: %v", secretName, bindingNamespace, err)
+		return
+	}
+	if _, ok := secret.Labels[binding.BindingLabel]; !ok {
+		log.Errorf("Secret [%v] was not created by 'apb binding add', refusing to remove it", secretName)
+		return
+	}
+	consumers, err := binding.FindConsumers(bindingNamespace)
+	if err != nil {
+		log.Errorf("Unable to find workloads consuming binding [%v]: %v", secretName, err)
+		return
+	}
+	for _, c := range consumers[secretName] {
+		if !c.Injected {
+			log.Warnf("[%v] references secret [%v] but it was not injected by apb. Remove the reference manually.", c.Workload, secretName)
+			continue
+		}
+		err = binding.Uninject(bindingNamespace, c.Workload, secretName)
+		if err != nil {
+			log.Errorf("Unable to remove secret [%v] from [%v]: %v", secretName, c.Workload, err)
+			return
+		}
+		fmt.Printf("Removed secret [%v] from [%v]\n", secretName, c.Workload)
+	}
+	err = k8scli.Client.CoreV1().Secrets(bindingNamespace).Delete(secretName, &metav1.DeleteOptions{})
+	if err != nil {
+		log.Errorf("Unable to delete secret [%v] in namespace [%v]: %v", secretName, bindingNamespace, err)
+		return
 	}
-	return &bundle.ExtractedCredentials{Credentials: creds}, nil
+	fmt.Printf("Successfully removed binding [%v] from namespace [%v]\n", secretName, bindingNamespace)
 }
@@ -23,9 +23,12 @@ import (
 	"errors"
 	"fmt"
 	yaml "gopkg.in/yaml.v2"
+	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/automationbroker/apb/pkg/config"
 
@@ -39,6 +42,16 @@ import (
 
 var brokerNamespaceFlag string
 var catalogOutputFormat string
+var bootstrapWait bool
+var bootstrapWaitTimeout time.Duration
+var bootstrapRelist bool
+
+const bootstrapPollInterval = 5 * time.Second
+
+type bootstrapResponse struct {
+	SpecCount  int `json:"spec_count"`
+	ImageCount int `json:"image_count"`
+}
 
 type ServiceEncoder interface {
 	Encode(interface{}) error
@@ -76,6 +89,9 @@ func init() {
 	brokerCatalogCmd.Flags().StringVarP(&catalogOutputFormat, "output", "o", "", "Display broker catalog output in different format (json or yaml)")
 	brokerCmd.AddCommand(brokerCatalogCmd)
 
+	brokerBootstrapCmd.Flags().BoolVarP(&bootstrapWait, "wait", "w", false, "Wait for the broker catalog to change and report added, removed or changed APBs")
+	brokerBootstrapCmd.Flags().DurationVar(&bootstrapWaitTimeout, "wait-timeout", 2*time.Minute, "How long to wait for the broker catalog to change")
+	brokerBootstrapCmd.Flags().BoolVar(&bootstrapRelist, "relist", false, "Relist the OpenShift Service Catalog after bootstrapping")
 	brokerCmd.AddCommand(brokerBootstrapCmd)
 	rootCmd.AddCommand(createHiddenCmd(brokerBootstrapCmd, "running 'apb broker bootstrap'"))
 }
@@ -114,32 +130,13 @@ func listBrokerCatalog(brokerRouteName string, brokerNamespace string) {
 		return
 	}
 
-	osbConf := &osb.ClientConfiguration{
-		Name:                "automation-broker",
-		URL:                 brokerRoute,
-		APIVersion:          osb.LatestAPIVersion(),
-		TimeoutSeconds:      60,
-		EnableAlphaFeatures: false,
-		Insecure:            true,
-		AuthConfig: &osb.AuthConfig{
-			BearerConfig: &osb.BearerConfig{
-				Token: kube.ClientConfig.BearerToken,
-			},
-		},
-	}
-	osbClient, err := osb.NewClient(osbConf)
-	if err != nil {
-		log.Errorf("Failed to make osb client: %v", err)
-		return
-	}
-
-	services, err := osbClient.GetCatalog()
+	services, err := getBrokerCatalog(brokerRoute, kube.ClientConfig.BearerToken)
 	if err != nil {
 		log.Errorf("Failed fetch catalog: %v", err)
 		return
 	}
 
-	printServices(services.Services, catalogOutputFormat)
+	printServices(services, catalogOutputFormat)
 	return
 }
 
@@ -170,6 +167,16 @@ func bootstrapBroker(brokerRouteName string, brokerNamespace string) {
 		return
 	}
 
+	// Snapshot the catalog so that changes can be reported after bootstrap
+	var previousServices []osb.Service
+	if bootstrapWait {
+		previousServices, err = getBrokerCatalog(brokerRoute, kube.ClientConfig.BearerToken)
+		if err != nil {
+			log.Errorf("Failed to fetch catalog before bootstrap: %v", err)
+			return
+		}
+	}
+
 	// Create a new bootstrap request
 	req, err := http.NewRequest("POST", fmt.Sprintf("%v/v2/bootstrap", brokerRoute), nil)
 	if err != nil {
@@ -193,13 +200,14 @@ func bootstrapBroker(brokerRouteName string, brokerNamespace string) {
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Errorf("Failed to get response: %v", err)
+		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 504 {
 		log.Errorf("Timed out waiting for broker bootstrap response.")
 		fmt.Print("Try increasing the route timeout with:\n")
-		fmt.Printf("oc annotate route asb -n %v --overwrite haproxy.router.openshift.io/timeout=60s\n", brokerRouteName)
+		fmt.Printf("oc annotate route %v -n %v --overwrite haproxy.router.openshift.io/timeout=60s\n", brokerRouteName, brokerNamespace)
 		return
 	}
 
@@ -208,10 +216,126 @@ func bootstrapBroker(brokerRouteName string, brokerNamespace string) {
 		return
 	}
 
-	fmt.Printf("Successfully started bootstrap job for broker [%v]\n", brokerRouteName)
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("Failed to read bootstrap response body: %v", err)
+		return
+	}
+	bootstrapResp := bootstrapResponse{}
+	err = json.Unmarshal(respBody, &bootstrapResp)
+	if err != nil {
+		log.Errorf("Failed to unmarshal bootstrap response: %v", err)
+		return
+	}
+	fmt.Printf("Successfully bootstrapped broker [%v]: loaded %v APB specs from %v images\n", brokerRouteName, bootstrapResp.SpecCount, bootstrapResp.ImageCount)
+
+	if bootstrapWait {
+		waitForCatalogChange(brokerRoute, kube.ClientConfig.BearerToken, previousServices)
+	}
+
+	if bootstrapRelist {
+		relistCatalog(config.LoadedDefaults.BrokerResourceURL, config.LoadedDefaults.ClusterServiceBrokerName)
+	}
 	return
 }
 
+// Poll the broker catalog until it differs from previousServices or the timeout expires
+func waitForCatalogChange(brokerRoute string, token string, previousServices []osb.Service) {
+	fmt.Printf("Waiting up to %v for the broker catalog to change...\n", bootstrapWaitTimeout)
+	deadline := time.Now().Add(bootstrapWaitTimeout)
+	for {
+		services, err := getBrokerCatalog(brokerRoute, token)
+		if err != nil {
+			log.Errorf("Failed to fetch catalog: %v", err)
+			return
+		}
+		added, removed, changed := diffServices(previousServices, services)
+		if len(added) > 0 || len(removed) > 0 || len(changed) > 0 {
+			printServiceChanges(added, removed, changed)
+			return
+		}
+		if time.Now().After(deadline) {
+			fmt.Printf("Broker catalog unchanged after %v. Catalog contains %v APBs.\n", bootstrapWaitTimeout, len(services))
+			return
+		}
+		log.Debugf("Broker catalog unchanged, polling again in %v", bootstrapPollInterval)
+		time.Sleep(bootstrapPollInterval)
+	}
+}
+
+// Returns services present only in newServices (added), only in oldServices (removed), and in both
+// with different plans, version or other metadata (changed)
+func diffServices(oldServices []osb.Service, newServices []osb.Service) (added []osb.Service, removed []osb.Service, changed []osb.Service) {
+	oldByID := map[string]osb.Service{}
+	newIDs := map[string]bool{}
+	for _, s := range oldServices {
+		oldByID[s.ID] = s
+	}
+	for _, s := range newServices {
+		newIDs[s.ID] = true
+		old, ok := oldByID[s.ID]
+		if !ok {
+			added = append(added, s)
+		} else if !reflect.DeepEqual(old, s) {
+			changed = append(changed, s)
+		}
+	}
+	for _, s := range oldServices {
+		if !newIDs[s.ID] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed, changed
+}
+
+func printServiceChanges(added []osb.Service, removed []osb.Service, changed []osb.Service) {
+	colChange := &util.TableColumn{Header: "CHANGE"}
+	colName := &util.TableColumn{Header: "NAME"}
+	colID := &util.TableColumn{Header: "ID"}
+
+	addRows := func(change string, services []osb.Service) {
+		for _, s := range services {
+			colChange.Data = append(colChange.Data, change)
+			colName.Data = append(colName.Data, s.Name)
+			colID.Data = append(colID.Data, s.ID)
+		}
+	}
+	addRows("added", added)
+	addRows("removed", removed)
+	addRows("changed", changed)
+
+	fmt.Printf("Broker catalog changed: %v APBs added, %v APBs removed, %v APBs changed\n", len(added), len(removed), len(changed))
+	tableToPrint := []*util.TableColumn{colChange, colName, colID}
+	util.PrintTable(tableToPrint)
+}
+
+// Fetch the list of services in the broker catalog over the OSB API
+func getBrokerCatalog(brokerRoute string, token string) ([]osb.Service, error) {
+	osbConf := &osb.ClientConfiguration{
+		Name:                "automation-broker",
+		URL:                 brokerRoute,
+		APIVersion:          osb.LatestAPIVersion(),
+		TimeoutSeconds:      60,
+		EnableAlphaFeatures: false,
+		Insecure:            true,
+		AuthConfig: &osb.AuthConfig{
+			BearerConfig: &osb.BearerConfig{
+				Token: token,
+			},
+		},
+	}
+	osbClient, err := osb.NewClient(osbConf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make osb client: %v", err)
+	}
+
+	catalog, err := osbClient.GetCatalog()
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Services, nil
+}
+
 func printServices(services []osb.Service, format string) {
 	var encoder ServiceEncoder
 	buffer := new(bytes.Buffer)
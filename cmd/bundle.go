@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/registries"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -74,7 +76,7 @@ var bundleInfoCmd = &cobra.Command{
 	Use:   "info <apb-name>",
 	Short: "Print info on APB image",
 	Long:  `Print metadata, plans, and params associated with an APB image`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		showBundleInfo(args[0], bundleRegistry)
 	},
@@ -84,24 +86,66 @@ var bundleNamespace string
 var sandboxRole string
 var printLogs bool
 var skipParams bool
+var bundleParams []string
+var inlineExtraVars bool
+var answersFile string
+var deprovisionInstanceID string
+var deprovisionSelector string
+var deprovisionAll bool
+var logTimestamps bool
+var logFile string
+var waitForBundle bool
+var bundleTargetNamespaces []string
+var bundleSandboxNamespace string
+var keepNamespaceOnError bool
+var bundleStateNamespace string
+var bundleDryRun string
+var bundleExecutor string
+
+// Pod customization flags, overriding the pod settings in defaults.json
+var podPullPolicy string
+var podCPURequest string
+var podCPULimit string
+var podMemoryRequest string
+var podMemoryLimit string
+var podNodeSelector []string
+var podTolerations []string
+var podRunAsUser int64
+var podRunAsNonRoot bool
+var podFSGroup int64
+var podLabels []string
+var podAnnotations []string
+var podEnv []string
+var podEnvFromSecrets []string
+var podEnvFromConfigMaps []string
+
+// Flags of the commands customizing the pod, to tell flags set to their default value from unset ones
+var podFlagSets []*pflag.FlagSet
+
+var httpProxy string
+var httpsProxy string
+var noProxy string
+var saveAnswersFile string
 
 var bundleProvisionCmd = &cobra.Command{
 	Use:   "provision <apb-name>",
 	Short: "Provision APB images",
 	Long:  `Provision an APB from a registry adapter`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		executeBundle("provision", args)
 	},
 }
 
 var bundleDeprovisionCmd = &cobra.Command{
-	Use:   "deprovision <bundle-name>",
+	Use:   "deprovision [bundle-name]",
 	Short: "Deprovision APB images",
-	Long:  `Deprovision an APB from a registry adapter`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Deprovision an APB from a registry adapter.
+Instances are found from this machine's provisioned instances and from provision pods in the namespace,
+so instances provisioned by other users can be deprovisioned too`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		executeBundle("deprovision", args)
+		deprovisionBundles(args)
 	},
 }
 
@@ -109,15 +153,24 @@ var bundleTestCmd = &cobra.Command{
 	Use:   "test <apb-name>",
 	Short: "test APB images",
 	Long:  `Test an APB from a registry adapter`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		local := bundleExecutor != runner.ExecutorKubernetes
 		pn := executeBundle("test", args)
 		if pn == "" {
 			log.Errorf("Failed to execute bundle")
 			return
 		}
-		//using bundleNamespace here is safe because executeBundle ensures it's not empty
-		succeed := checkTestSucceeded(pn, bundleNamespace)
+		if bundleDryRun != "" {
+			return
+		}
+		// The pod of a transient namespace is gone and local containers have no pod, RunBundle
+		// waited for them and failed if they did
+		succeed := bundleSandboxNamespace != "" || local
+		if !succeed {
+			//using bundleNamespace here is safe because executeBundle ensures it's not empty
+			succeed = checkTestSucceeded(pn, bundleNamespace)
+		}
 		if succeed {
 			fmt.Printf("Test succeeded for bundle [%v]\n", args[0])
 			return
@@ -127,6 +180,17 @@ var bundleTestCmd = &cobra.Command{
 	},
 }
 
+var bundleLogsCmd = &cobra.Command{
+	Use:   "logs <instance-id|pod-name>",
+	Short: "Print logs of APB pods",
+	Long: `Print the logs of an APB pod, or of all action pods of an instance, oldest first.
+Use --follow to stream the logs of a running pod.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showBundleLogs(args[0])
+	},
+}
+
 var bundleInitStub = &cobra.Command{
 	Use:        "init <bundle-name>",
 	Deprecated: "use 'ansible-galaxy init --type=apb <bundle-name>'",
@@ -172,6 +236,19 @@ func init() {
 	bundleProvisionCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
 	bundleProvisionCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
 	bundleProvisionCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from provision pod")
+	bundleProvisionCmd.Flags().StringArrayVarP(&bundleParams, "param", "p", []string{}, "Parameter value as name=value, or a reference to it as name=@secret:ns/secret/key, name=@file:/path or name=@env:VAR. May be repeated")
+	bundleProvisionCmd.Flags().BoolVar(&inlineExtraVars, "inline-extra-vars", false, "Pass extra-vars as a pod argument instead of through a secret, for images based on older apb-base")
+	bundleProvisionCmd.Flags().StringArrayVar(&bundleTargetNamespaces, "target-namespace", []string{}, "Namespace the APB acts on besides --namespace, where its sandbox role is also granted. May be repeated")
+	addSandboxFlags(bundleProvisionCmd)
+	addStateFlags(bundleProvisionCmd.Flags())
+	addDryRunFlag(bundleProvisionCmd)
+	bundleProvisionCmd.Flags().StringVar(&bundleExecutor, "executor", runner.ExecutorKubernetes, "Run the APB as a pod with kubernetes, or in a local container with docker or podman")
+	addPodFlags(bundleProvisionCmd)
+	addEnvFlags(bundleProvisionCmd)
+	addLogFlags(bundleProvisionCmd)
+	bundleProvisionCmd.Flags().BoolVar(&waitForBundle, "wait", false, "Wait for the APB to finish, showing its progress")
+	bundleProvisionCmd.Flags().StringVar(&answersFile, "answers", "", "Replay the plan and parameters from an answers file")
+	bundleProvisionCmd.Flags().StringVar(&saveAnswersFile, "save-answers", "", "Save the chosen plan and parameters to an answers file")
 	rootCmd.AddCommand(createHiddenCmd(bundleProvisionCmd, ""))
 	bundleCmd.AddCommand(bundleProvisionCmd)
 
@@ -179,6 +256,19 @@ func init() {
 	bundleTestCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
 	bundleTestCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
 	bundleTestCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from provision pod")
+	bundleTestCmd.Flags().StringArrayVarP(&bundleParams, "param", "p", []string{}, "Parameter value as name=value, or a reference to it as name=@secret:ns/secret/key, name=@file:/path or name=@env:VAR. May be repeated")
+	bundleTestCmd.Flags().BoolVar(&inlineExtraVars, "inline-extra-vars", false, "Pass extra-vars as a pod argument instead of through a secret, for images based on older apb-base")
+	bundleTestCmd.Flags().StringArrayVar(&bundleTargetNamespaces, "target-namespace", []string{}, "Namespace the APB acts on besides --namespace, where its sandbox role is also granted. May be repeated")
+	addSandboxFlags(bundleTestCmd)
+	addStateFlags(bundleTestCmd.Flags())
+	addDryRunFlag(bundleTestCmd)
+	bundleTestCmd.Flags().StringVar(&bundleExecutor, "executor", runner.ExecutorKubernetes, "Run the APB as a pod with kubernetes, or in a local container with docker or podman")
+	addPodFlags(bundleTestCmd)
+	addEnvFlags(bundleTestCmd)
+	addLogFlags(bundleTestCmd)
+	bundleTestCmd.Flags().BoolVar(&waitForBundle, "wait", false, "Wait for the APB to finish, showing its progress")
+	bundleTestCmd.Flags().StringVar(&answersFile, "answers", "", "Replay the plan and parameters from an answers file")
+	bundleTestCmd.Flags().StringVar(&saveAnswersFile, "save-answers", "", "Save the chosen plan and parameters to an answers file")
 	rootCmd.AddCommand(createHiddenCmd(bundleTestCmd, "running `apb bundle test` instead."))
 	bundleCmd.AddCommand(bundleTestCmd)
 
@@ -186,10 +276,29 @@ func init() {
 	bundleDeprovisionCmd.Flags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandbox")
 	bundleDeprovisionCmd.Flags().StringVarP(&bundleRegistry, "registry", "r", "", "Registry to load APB from")
 	bundleDeprovisionCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Print logs from deprovision pod")
+	bundleDeprovisionCmd.Flags().StringArrayVarP(&bundleParams, "param", "p", []string{}, "Parameter value as name=value, or a reference to it as name=@secret:ns/secret/key, name=@file:/path or name=@env:VAR. May be repeated")
+	bundleDeprovisionCmd.Flags().BoolVar(&inlineExtraVars, "inline-extra-vars", false, "Pass extra-vars as a pod argument instead of through a secret, for images based on older apb-base")
+	bundleDeprovisionCmd.Flags().StringArrayVar(&bundleTargetNamespaces, "target-namespace", []string{}, "Namespace the APB acts on besides --namespace. Defaults to the target namespaces recorded at provision. May be repeated")
+	addSandboxFlags(bundleDeprovisionCmd)
+	addStateFlags(bundleDeprovisionCmd.Flags())
+	addDryRunFlag(bundleDeprovisionCmd)
+	bundleDeprovisionCmd.Flags().StringVar(&bundleExecutor, "executor", runner.ExecutorKubernetes, "Run the APB as a pod with kubernetes, or in a local container with docker or podman")
+	addPodFlags(bundleDeprovisionCmd)
+	addEnvFlags(bundleDeprovisionCmd)
+	addLogFlags(bundleDeprovisionCmd)
+	bundleDeprovisionCmd.Flags().BoolVar(&waitForBundle, "wait", false, "Wait for the APB to finish, showing its progress")
 	bundleDeprovisionCmd.Flags().BoolVar(&skipParams, "skip-params", false, "Don't prompt for parameters")
+	bundleDeprovisionCmd.Flags().StringVar(&deprovisionInstanceID, "instance-id", "", "ID of the instance to deprovision")
+	bundleDeprovisionCmd.Flags().StringVarP(&deprovisionSelector, "selector", "l", "", "Label selector matching the provision pods of the instances to deprovision, e.g. bundle-fqname=postgresql-apb")
+	bundleDeprovisionCmd.Flags().BoolVar(&deprovisionAll, "all", false, "Deprovision all matching instances")
 	rootCmd.AddCommand(createHiddenCmd(bundleDeprovisionCmd, ""))
 	bundleCmd.AddCommand(bundleDeprovisionCmd)
 
+	bundleLogsCmd.Flags().StringVarP(&bundleNamespace, "namespace", "n", "", "Namespace of the APB pods")
+	bundleLogsCmd.Flags().BoolVarP(&printLogs, "follow", "f", false, "Stream the logs until the pod finishes")
+	addLogFlags(bundleLogsCmd)
+	bundleCmd.AddCommand(bundleLogsCmd)
+
 	rootCmd.AddCommand(bundleInitStub)
 	bundleCmd.AddCommand(bundleInitStub)
 
@@ -237,32 +346,76 @@ func ListImages() {
 }
 
 func executeBundle(action string, args []string) (podName string) {
+	if !resolveBundleNamespace() {
+		return ""
+	}
+	return runBundle(action, args[0], "")
+}
+
+// Default bundleNamespace to the current namespace, returning false if it can't be determined
+func resolveBundleNamespace() bool {
 	if bundleNamespace == "" {
 		bundleNamespace = util.GetCurrentNamespace(kubeConfig)
 		if bundleNamespace == "" {
 			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
-			return ""
+			return false
 		}
 	}
-	log.Debugf("Running bundle [%v] with action [%v] in namespace [%v].", args[0], action, bundleNamespace)
-	pn, err := runner.RunBundle(action, bundleNamespace, args[0], sandboxRole, bundleRegistry, printLogs, skipParams, args[1:])
+	return true
+}
+
+// Run an APB action and keep the list of provisioned instances up to date
+func runBundle(action string, bundleName string, instanceID string) (podName string) {
+	log.Debugf("Running bundle [%v] with action [%v] in namespace [%v].", bundleName, action, bundleNamespace)
+	opts := runner.RunOptions{
+		Params:               bundleParams,
+		InlineExtraVars:      inlineExtraVars,
+		AnswersFile:          answersFile,
+		SaveAnswersFile:      saveAnswersFile,
+		InstanceID:           instanceID,
+		Pod:                  podSettings(),
+		Env:                  podEnv,
+		EnvFromSecrets:       podEnvFromSecrets,
+		EnvFromConfigMaps:    podEnvFromConfigMaps,
+		Proxy:                proxySettings(),
+		Logs:                 runner.LogOptions{Timestamps: logTimestamps, LogFile: logFile},
+		Wait:                 waitForBundle,
+		TargetNamespaces:     bundleTargetNamespaces,
+		SandboxNamespace:     bundleSandboxNamespace,
+		KeepNamespaceOnError: keepNamespaceOnError,
+		StateNamespace:       stateNamespaceSetting(),
+		DryRun:               bundleDryRun,
+		Executor:             bundleExecutor,
+	}
+	// Transient namespaces and local containers leave no pod to read the last operation from later
+	lastOperation := ""
+	opts.Finished = func(result runner.Result) {
+		lastOperation = result.Description
+	}
+	pn, err := runner.RunBundle(action, bundleNamespace, bundleName, sandboxRole, bundleRegistry, printLogs, skipParams, opts)
+	if _, ok := err.(runner.SandboxError); ok {
+		fmt.Printf("\nProblem creating sandbox to run APB. Did you run `oc new-project %s` first?\n\n", bundleNamespace)
+	}
 	if err != nil {
-		log.Errorf("Failed to execute bundle [%v]: %v", args[0], err)
+		log.Errorf("Failed to execute bundle [%v]: %v", bundleName, err)
 		return ""
 	}
+	if bundleDryRun != "" {
+		return pn
+	}
 	id := strings.Split(pn, "bundle-")[1]
 	id = strings.TrimPrefix(id, "provision-")
 	id = strings.TrimPrefix(id, "deprovision-")
 	switch action {
 	case "provision":
 		// Add instance to ProvisionedInstances
-		err = addInstance(args[0], bundleNamespace, id)
+		err = addInstance(bundleName, bundleNamespace, id, bundleTargetNamespaces, lastOperation)
 		if err != nil {
 			log.Errorf("Failed to add instance ID to list of provisioned instances")
 		}
 	case "deprovision":
 		// Remove instance from ProvisionedInstances
-		err = removeInstance(args[0], bundleNamespace, id)
+		err = removeInstance(bundleName, bundleNamespace, id)
 		if err != nil {
 			log.Errorf("Failed to remove instance ID from list of provisioned instances")
 		}
@@ -270,6 +423,234 @@ func executeBundle(action string, args []string) (podName string) {
 	return pn
 }
 
+// Add the flags customizing the APB pod to an action command
+func addPodFlags(cmd *cobra.Command) {
+	podFlagSets = append(podFlagSets, cmd.Flags())
+	cmd.Flags().StringVar(&podPullPolicy, "image-pull-policy", "", "Image pull policy of the APB pod: Always, IfNotPresent or Never (default Always)")
+	cmd.Flags().StringVar(&podCPURequest, "cpu-request", "", "CPU request of the APB pod, e.g. 100m")
+	cmd.Flags().StringVar(&podCPULimit, "cpu-limit", "", "CPU limit of the APB pod, e.g. 500m")
+	cmd.Flags().StringVar(&podMemoryRequest, "memory-request", "", "Memory request of the APB pod, e.g. 128Mi")
+	cmd.Flags().StringVar(&podMemoryLimit, "memory-limit", "", "Memory limit of the APB pod, e.g. 512Mi")
+	cmd.Flags().StringArrayVar(&podNodeSelector, "node-selector", []string{}, "Node selector of the APB pod as key=value. May be repeated")
+	cmd.Flags().StringArrayVar(&podTolerations, "toleration", []string{}, "Toleration of the APB pod as key[=value]:effect[:seconds]. May be repeated")
+	cmd.Flags().Int64Var(&podRunAsUser, "run-as-user", -1, "User ID to run the APB pod as")
+	cmd.Flags().BoolVar(&podRunAsNonRoot, "run-as-non-root", false, "Require the APB pod to run as a non-root user")
+	cmd.Flags().Int64Var(&podFSGroup, "fs-group", -1, "Supplemental group applied to the volumes of the APB pod")
+	cmd.Flags().StringArrayVar(&podLabels, "pod-label", []string{}, "Extra label of the APB pod as key=value. May be repeated")
+	cmd.Flags().StringArrayVar(&podAnnotations, "pod-annotation", []string{}, "Extra annotation of the APB pod as key=value. May be repeated")
+}
+
+// Add the flags running an action in a transient namespace to an action command
+func addSandboxFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&bundleSandboxNamespace, "sandbox-namespace", "", "Run the APB pod in a transient namespace, deleted once it finished: 'auto' or a name prefix")
+	cmd.Flags().BoolVar(&keepNamespaceOnError, "keep-namespace-on-error", false, "Keep the transient namespace of a failed APB")
+}
+
+// Add the flag choosing where the state of instances is kept to a command
+func addStateFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&bundleStateNamespace, "state-namespace", "", "Namespace keeping the state of APB instances, instead of the configured one or the instance namespace")
+}
+
+// Add the flag printing the objects of an action instead of creating them to an action command
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&bundleDryRun, "dry-run", "", "Print the pod, sandbox and redacted extra-vars as YAML instead of running the APB: client, or server to also submit them with the API server dry-run")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = runner.DryRunClient
+}
+
+// Master state namespace from the flag or the configured defaults
+func stateNamespaceSetting() string {
+	if bundleStateNamespace != "" {
+		return bundleStateNamespace
+	}
+	return config.LoadedDefaults.StateNamespace
+}
+
+// Add the flags setting the environment of the APB pod to an action command
+func addEnvFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&podEnv, "env", []string{}, "Environment variable of the APB pod as KEY=VALUE. May be repeated")
+	cmd.Flags().StringArrayVar(&podEnvFromSecrets, "env-from-secret", []string{}, "Secret in the namespace whose keys are set as environment variables of the APB pod. May be repeated")
+	cmd.Flags().StringArrayVar(&podEnvFromConfigMaps, "env-from-configmap", []string{}, "ConfigMap in the namespace whose keys are set as environment variables of the APB pod. May be repeated")
+	cmd.Flags().StringVar(&httpProxy, "http-proxy", "", "HTTP proxy for the APB pod, overriding the one from 'apb config'")
+	cmd.Flags().StringVar(&httpsProxy, "https-proxy", "", "HTTPS proxy for the APB pod, overriding the one from 'apb config'")
+	cmd.Flags().StringVar(&noProxy, "no-proxy", "", "Hosts the APB pod reaches without proxy, overriding the ones from 'apb config'")
+}
+
+// Add the flags controlling how APB pod logs are printed to a command
+func addLogFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&logTimestamps, "timestamps", false, "Prefix every log line with its timestamp")
+	cmd.Flags().StringVar(&logFile, "log-file", "", "Also append the APB logs to a file")
+}
+
+// Merge the proxy flags into the proxy settings from defaults.json
+func proxySettings() config.ProxySettings {
+	settings := config.LoadedDefaults.Proxy
+	if httpProxy != "" {
+		settings.HTTPProxy = httpProxy
+	}
+	if httpsProxy != "" {
+		settings.HTTPSProxy = httpsProxy
+	}
+	if noProxy != "" {
+		settings.NoProxy = noProxy
+	}
+	return settings
+}
+
+// Merge the pod customization flags into the pod settings from defaults.json
+func podSettings() config.PodSettings {
+	settings := config.LoadedDefaults.Pod
+	if podPullPolicy != "" {
+		settings.ImagePullPolicy = podPullPolicy
+	}
+	if podCPURequest != "" {
+		settings.CPURequest = podCPURequest
+	}
+	if podCPULimit != "" {
+		settings.CPULimit = podCPULimit
+	}
+	if podMemoryRequest != "" {
+		settings.MemoryRequest = podMemoryRequest
+	}
+	if podMemoryLimit != "" {
+		settings.MemoryLimit = podMemoryLimit
+	}
+	if podRunAsUser >= 0 {
+		settings.RunAsUser = &podRunAsUser
+	}
+	if podFSGroup >= 0 {
+		settings.FSGroup = &podFSGroup
+	}
+	// --run-as-non-root=false turns off the default too
+	if podFlagChanged("run-as-non-root") {
+		settings.RunAsNonRoot = podRunAsNonRoot
+	}
+	// Later key=value pairs win, so flags override defaults with the same key
+	settings.NodeSelector = append(append([]string{}, settings.NodeSelector...), podNodeSelector...)
+	settings.Tolerations = append(append([]string{}, settings.Tolerations...), podTolerations...)
+	settings.Labels = append(append([]string{}, settings.Labels...), podLabels...)
+	settings.Annotations = append(append([]string{}, settings.Annotations...), podAnnotations...)
+	return settings
+}
+
+// Tell whether a pod flag was given on the command line
+func podFlagChanged(name string) bool {
+	for _, flags := range podFlagSets {
+		if flags.Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Deprovision the instances selected by name, --instance-id, --selector and --all
+func deprovisionBundles(args []string) {
+	bundleName := ""
+	if len(args) == 1 {
+		bundleName = args[0]
+	}
+	if deprovisionInstanceID == "" && deprovisionSelector == "" {
+		if bundleName == "" {
+			log.Errorf("Specify the APB to deprovision, or select instances with --instance-id or --selector")
+			return
+		}
+		if !deprovisionAll {
+			executeBundle("deprovision", args)
+			return
+		}
+	}
+	if !resolveBundleNamespace() {
+		return
+	}
+
+	selector := deprovisionSelector
+	if bundleName != "" {
+		if selector != "" {
+			selector += ","
+		}
+		selector += fmt.Sprintf("%v=%v", runner.BundleFQNameLabel, bundleName)
+	}
+	instances, err := runner.ListInstances(bundleNamespace, selector)
+	if err != nil {
+		log.Errorf("Failed to list instances in namespace [%v]: %v", bundleNamespace, err)
+		return
+	}
+	if deprovisionInstanceID != "" {
+		matching := []runner.Instance{}
+		for _, instance := range instances {
+			if instance.ID == deprovisionInstanceID {
+				matching = append(matching, instance)
+			}
+		}
+		instances = matching
+	}
+	if len(instances) == 0 {
+		log.Errorf("Found no matching instances in namespace [%v]", bundleNamespace)
+		return
+	}
+	if len(instances) > 1 && !deprovisionAll {
+		fmt.Printf("Found %v matching instances:\n", len(instances))
+		printInstances(instances)
+		log.Errorf("Use --all to deprovision all of them, or pick one with --instance-id")
+		return
+	}
+
+	failed := 0
+	for _, instance := range instances {
+		fmt.Printf("Deprovisioning instance [%v] of APB [%v]\n", instance.ID, instance.BundleName)
+		if runBundle("deprovision", instance.BundleName, instance.ID) == "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Errorf("Failed to deprovision %v of %v instances", failed, len(instances))
+	}
+}
+
+func printInstances(instances []runner.Instance) {
+	colID := &util.TableColumn{Header: "INSTANCE ID"}
+	colBundle := &util.TableColumn{Header: "APB"}
+	colPhase := &util.TableColumn{Header: "PROVISION POD"}
+	colLocal := &util.TableColumn{Header: "LOCAL"}
+	colLastOp := &util.TableColumn{Header: "LAST OPERATION"}
+	for _, instance := range instances {
+		colID.Data = append(colID.Data, instance.ID)
+		colBundle.Data = append(colBundle.Data, instance.BundleName)
+		phase := instance.Phase
+		if phase == "" {
+			phase = "<not found>"
+		}
+		colPhase.Data = append(colPhase.Data, phase)
+		colLocal.Data = append(colLocal.Data, strconv.FormatBool(instance.Local))
+		colLastOp.Data = append(colLastOp.Data, instance.LastOperation)
+	}
+	tableToPrint := []*util.TableColumn{colID, colBundle, colPhase, colLocal, colLastOp}
+	util.PrintTable(tableToPrint)
+}
+
+// Print the logs of an APB pod, or of every action pod of an instance
+func showBundleLogs(nameOrID string) {
+	if !resolveBundleNamespace() {
+		return
+	}
+	pods, err := runner.FindActionPods(bundleNamespace, nameOrID)
+	if err != nil {
+		log.Errorf("Failed to find APB pods in namespace [%v]: %v", bundleNamespace, err)
+		return
+	}
+	if len(pods) == 0 {
+		log.Errorf("Found no APB pod or instance [%v] in namespace [%v]", nameOrID, bundleNamespace)
+		return
+	}
+	opts := runner.LogOptions{Follow: printLogs, Timestamps: logTimestamps, LogFile: logFile}
+	for _, pod := range pods {
+		fmt.Printf("APB %v pod [%v] (%v)\n", pod.Labels[runner.BundleActionLabel], pod.Name, pod.Status.Phase)
+		err = runner.PrintLogs(pod.Name, bundleNamespace, opts)
+		if err != nil {
+			log.Errorf("Failed to print logs of pod [%v]: %v", pod.Name, err)
+		}
+	}
+}
+
 // Check running pod if it has succeeded or not
 func checkTestSucceeded(podName string, namespace string) bool {
 	log.Infof("Monitoring test pod [%v] for status every 5 seconds...", podName)
@@ -498,7 +879,8 @@ func processLineBreaks(text []byte, lineBreakText []byte, breakAfter int) []byte
 	return newText
 }
 
-func addInstance(name, namespace, id string) error {
+// Record a provisioned instance and the extra namespaces it targets
+func addInstance(name, namespace, id string, targets []string, lastOperation string) error {
 	var instanceConfigs []config.ProvisionedInstance
 	err := config.ProvisionedInstances.UnmarshalKey("ProvisionedInstances", &instanceConfigs)
 	if err != nil {
@@ -509,6 +891,18 @@ func addInstance(name, namespace, id string) error {
 		if instance.BundleName == name {
 			log.Debugf("Adding instance")
 			instanceConfigs[i].InstanceIDs[namespace] = append(instance.InstanceIDs[namespace], id)
+			if len(targets) > 0 {
+				if instance.TargetNamespaces == nil {
+					instanceConfigs[i].TargetNamespaces = map[string][]string{}
+				}
+				instanceConfigs[i].TargetNamespaces[id] = targets
+			}
+			if lastOperation != "" {
+				if instance.LastOperations == nil {
+					instanceConfigs[i].LastOperations = map[string]string{}
+				}
+				instanceConfigs[i].LastOperations[id] = lastOperation
+			}
 			err = config.UpdateCachedInstances(config.ProvisionedInstances, instanceConfigs)
 			if err != nil {
 				return err
@@ -523,6 +917,12 @@ func addInstance(name, namespace, id string) error {
 		BundleName:  name,
 		InstanceIDs: idMap,
 	}
+	if len(targets) > 0 {
+		instance.TargetNamespaces = map[string][]string{id: targets}
+	}
+	if lastOperation != "" {
+		instance.LastOperations = map[string]string{id: lastOperation}
+	}
 
 	instanceConfigs = append(instanceConfigs, instance)
 	err = config.UpdateCachedInstances(config.ProvisionedInstances, instanceConfigs)
@@ -546,6 +946,8 @@ func removeInstance(name, namespace, id string) error {
 				if instanceID == id {
 					// Remove instance
 					instanceConfigs[i].InstanceIDs[namespace] = append(instance.InstanceIDs[namespace][:j], instance.InstanceIDs[namespace][j+1:]...)
+					delete(instanceConfigs[i].TargetNamespaces, id)
+					delete(instanceConfigs[i].LastOperations, id)
 					err = config.UpdateCachedInstances(config.ProvisionedInstances, instanceConfigs)
 					if err != nil {
 						return err
@@ -17,35 +17,35 @@
 package cmd
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/apb/pkg/servicecatalog"
+	"github.com/automationbroker/apb/pkg/util"
 
+	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/clients"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
-type clusterServiceBrokerSpec struct {
-	RelistRequests int
-}
-
-type relistResponse struct {
-	Kind string
-	Spec clusterServiceBrokerSpec
-}
-
 var brokerResourceName string
+var catalogRelistWait bool
+var catalogRelistWaitTimeout time.Duration
+var catalogBrokerScope string
+var catalogDiffRegistry string
+var catalogDiffAll bool
+var catalogNamespace string
+var catalogAllNamespaces bool
 
 var catalogCmd = &cobra.Command{
 	Use:   "catalog",
 	Short: "Interact with OpenShift Service Catalog",
-	Long:  `Force the OpenShift Service Catalog to relist its group of APB specs`,
+	Long:  `Relist or inspect the APB specs known to the OpenShift Service Catalog`,
 }
 
 var catalogRelistCmd = &cobra.Command{
@@ -57,11 +57,196 @@ var catalogRelistCmd = &cobra.Command{
 	},
 }
 
+var catalogDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare registry cache, broker catalog and service catalog",
+	Long:  `Compare the 'apb bundle list' cache, the Automation Broker catalog and the OpenShift Service Catalog to find APBs that are missing or stale at each layer`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		diffCatalogs(config.LoadedDefaults.BrokerRouteName, config.LoadedDefaults.BrokerNamespace, config.LoadedDefaults.ClusterServiceBrokerName)
+	},
+}
+
+var catalogClassesCmd = &cobra.Command{
+	Use:   "classes",
+	Short: "List service classes",
+	Long:  `List the ClusterServiceClasses known to the OpenShift Service Catalog and the broker that owns each one`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listCatalogClasses()
+	},
+}
+
+var catalogPlansCmd = &cobra.Command{
+	Use:   "plans [class-name]",
+	Short: "List service plans",
+	Long:  `List the ClusterServicePlans known to the OpenShift Service Catalog, optionally for a single class`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		className := ""
+		if len(args) > 0 {
+			className = args[0]
+		}
+		listCatalogPlans(className)
+	},
+}
+
+var catalogInstancesCmd = &cobra.Command{
+	Use:   "instances",
+	Short: "List service instances",
+	Long:  `List ServiceInstances with their provision status and last operation`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listCatalogInstances()
+	},
+}
+
+var catalogBindingsCmd = &cobra.Command{
+	Use:   "bindings",
+	Short: "List service bindings",
+	Long:  `List ServiceBindings with their status and last operation`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listCatalogBindings()
+	},
+}
+
+var catalogDescribeCmd = &cobra.Command{
+	Use:       "describe <class|plan|instance|binding> <name>",
+	Short:     "Describe a Service Catalog object",
+	Long:      `Print details and conditions of a single class, plan, instance or binding`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"class", "plan", "instance", "binding"},
+	Run: func(cmd *cobra.Command, args []string) {
+		describeCatalogObject(args[0], args[1])
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(catalogCmd)
 	// Catalog Relist Flags
 	catalogCmd.PersistentFlags().StringVarP(&brokerResourceName, "name", "n", "", "Name of Automation Broker resource")
+	catalogRelistCmd.Flags().BoolVarP(&catalogRelistWait, "wait", "w", false, "Wait for the broker to retrieve its catalog and report catalog errors")
+	catalogRelistCmd.Flags().DurationVar(&catalogRelistWaitTimeout, "wait-timeout", 2*time.Minute, "How long to wait for the relist to complete")
+	catalogRelistCmd.Flags().StringVar(&catalogBrokerScope, "scope", "", "Scope of the broker resource: cluster or namespace (default is the configured scope, auto-detected if unset)")
+	catalogRelistCmd.Flags().StringVar(&catalogNamespace, "namespace", "", "Namespace of a namespace-scoped broker (default is the current namespace)")
 	catalogCmd.AddCommand(catalogRelistCmd)
+
+	// Catalog Diff Flags
+	catalogDiffCmd.Flags().StringVarP(&catalogDiffRegistry, "registry", "r", "", "Only compare APBs cached from this registry")
+	catalogDiffCmd.Flags().BoolVarP(&catalogDiffAll, "all", "a", false, "Show APBs that are consistent across all layers")
+	catalogCmd.AddCommand(catalogDiffCmd)
+
+	catalogCmd.AddCommand(catalogClassesCmd)
+	catalogCmd.AddCommand(catalogPlansCmd)
+
+	// Catalog Instances, Bindings and Describe Flags
+	for _, c := range []*cobra.Command{catalogInstancesCmd, catalogBindingsCmd, catalogDescribeCmd} {
+		c.Flags().StringVar(&catalogNamespace, "namespace", "", "Namespace of instances and bindings (default is the current namespace)")
+		catalogCmd.AddCommand(c)
+	}
+	catalogInstancesCmd.Flags().BoolVar(&catalogAllNamespaces, "all-namespaces", false, "List instances in all namespaces")
+	catalogBindingsCmd.Flags().BoolVar(&catalogAllNamespaces, "all-namespaces", false, "List bindings in all namespaces")
+}
+
+func diffCatalogs(brokerRouteName string, brokerNamespace string, clusterServiceBrokerName string) {
+	log.Debug("diffCatalogs called")
+	// Override setting from config file when cmd arg provided
+	if brokerResourceName != "" {
+		clusterServiceBrokerName = brokerResourceName
+	}
+
+	var regConfigs []config.Registry
+	err := config.Registries.UnmarshalKey("Registries", &regConfigs)
+	if err != nil {
+		log.Error("Error unmarshalling config: ", err)
+		return
+	}
+	specs := []*bundle.Spec{}
+	for _, r := range regConfigs {
+		if catalogDiffRegistry != "" && r.Config.Name != catalogDiffRegistry {
+			continue
+		}
+		specs = append(specs, r.Specs...)
+	}
+
+	kube, err := clients.Kubernetes()
+	if err != nil {
+		log.Errorf("Failed to connect to cluster: %v", err)
+		return
+	}
+	// Check for user with valid bearer token
+	if kube.ClientConfig.BearerToken == "" {
+		handleBearerTokenErr()
+		return
+	}
+
+	brokerRoute, err := getBrokerRoute(brokerRouteName, brokerNamespace)
+	if err != nil {
+		log.Errorf("Failed to get broker route: %v", err)
+		if strings.Contains(err.Error(), "cannot list routes") {
+			handleResourceInaccessibleErr("routes", brokerRouteName, false)
+		}
+		return
+	}
+	services, err := getBrokerCatalog(brokerRoute, kube.ClientConfig.BearerToken)
+	if err != nil {
+		log.Errorf("Failed fetch broker catalog: %v", err)
+		return
+	}
+
+	classes, err := servicecatalog.ListClusterServiceClasses(clusterServiceBrokerName)
+	if err != nil {
+		log.Errorf("Failed to list clusterserviceclasses: %v", err)
+		if strings.Contains(err.Error(), "cannot list clusterserviceclasses") {
+			handleResourceInaccessibleErr("clusterserviceclasses", "", true)
+		}
+		return
+	}
+	plans, err := servicecatalog.ListClusterServicePlans(clusterServiceBrokerName)
+	if err != nil {
+		log.Errorf("Failed to list clusterserviceplans: %v", err)
+		if strings.Contains(err.Error(), "cannot list clusterserviceplans") {
+			handleResourceInaccessibleErr("clusterserviceplans", "", true)
+		}
+		return
+	}
+
+	entries := servicecatalog.DiffCatalogs(specs, services, classes, plans)
+	printCatalogDiff(entries, catalogDiffAll)
+}
+
+func printCatalogDiff(entries []servicecatalog.DiffEntry, showAll bool) {
+	colName := &util.TableColumn{Header: "APB"}
+	colCache := &util.TableColumn{Header: "CACHE"}
+	colBroker := &util.TableColumn{Header: "BROKER"}
+	colCatalog := &util.TableColumn{Header: "SERVICE CATALOG"}
+	colProblems := &util.TableColumn{Header: "PROBLEMS"}
+
+	problemCount := 0
+	for _, e := range entries {
+		if len(e.Problems) > 0 {
+			problemCount++
+		} else if !showAll {
+			continue
+		}
+		colName.Data = append(colName.Data, e.Name)
+		colCache.Data = append(colCache.Data, strconv.FormatBool(e.InCache))
+		colBroker.Data = append(colBroker.Data, strconv.FormatBool(e.InBroker))
+		colCatalog.Data = append(colCatalog.Data, strconv.FormatBool(e.InServiceCatalog))
+		colProblems.Data = append(colProblems.Data, strings.Join(e.Problems, "; "))
+	}
+
+	if problemCount == 0 {
+		fmt.Printf("All %v APBs are consistent across the registry cache, broker catalog and Service Catalog\n", len(entries))
+		if !showAll {
+			return
+		}
+	} else {
+		fmt.Printf("Found %v of %v APBs with differences between layers\n", problemCount, len(entries))
+	}
+	tableToPrint := []*util.TableColumn{colName, colCache, colBroker, colCatalog, colProblems}
+	util.PrintTable(tableToPrint)
 }
 
 func relistCatalog(brokerResourceURL string, clusterServiceBrokerName string) {
@@ -80,76 +265,417 @@ func relistCatalog(brokerResourceURL string, clusterServiceBrokerName string) {
 		handleBearerTokenErr()
 		return
 	}
-	// Get Cluster URL and form clusterservicebroker request
-	host := kube.ClientConfig.Host
-	brokerURL := fmt.Sprintf("%v%v%v", host, brokerResourceURL, clusterServiceBrokerName)
 
-	req, err := http.NewRequest("GET", brokerURL, nil)
+	brokerPath, brokerKind, ok := getBrokerResourcePath(brokerResourceURL, clusterServiceBrokerName)
+	if !ok {
+		return
+	}
+
+	broker, err := servicecatalog.GetBroker(brokerPath)
+	if err != nil {
+		handleRelistErr(brokerKind, brokerPath, err)
+		return
+	}
+	_, err = servicecatalog.RelistBroker(brokerPath)
 	if err != nil {
-		log.Errorf("Failed to create relist request: %v", err)
+		handleRelistErr(brokerKind, brokerPath, err)
 		return
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", kube.ClientConfig.BearerToken))
-	// Skip TLS for now
-	cfg := &tls.Config{
-		InsecureSkipVerify: true,
+	fmt.Printf("Successfully relisted OpenShift Service Catalog for %v [%v]\n", brokerKind, clusterServiceBrokerName)
+
+	if catalogRelistWait {
+		fmt.Printf("Waiting up to %v for [%v] to retrieve its catalog...\n", catalogRelistWaitTimeout, clusterServiceBrokerName)
+		broker, err = servicecatalog.WaitForBrokerRelist(brokerPath, broker.Status.LastCatalogRetrievalTime, catalogRelistWaitTimeout)
+		if err != nil {
+			log.Errorf("Relist of [%v] did not complete: %v", clusterServiceBrokerName, err)
+			return
+		}
+		fmt.Printf("Broker [%v] is ready, catalog retrieved at %v\n", clusterServiceBrokerName, broker.Status.LastCatalogRetrievalTime)
+	}
+	return
+}
+
+// Resolve the API path and resource kind of the broker to relist from --scope, or from the configured
+// scope, auto-detecting between a clusterservicebroker and a namespaced servicebroker if neither is set
+func getBrokerResourcePath(brokerResourceURL string, brokerName string) (path string, kind string, ok bool) {
+	scope := config.LoadedDefaults.BrokerScope
+	if catalogBrokerScope != "" {
+		scope = catalogBrokerScope
+	}
+	clusterPath := fmt.Sprintf("%v%v", brokerResourceURL, brokerName)
+
+	if scope == servicecatalog.BrokerScopeCluster {
+		return clusterPath, "clusterservicebroker", true
+	}
+	if scope != "" && scope != "auto" && scope != servicecatalog.BrokerScopeNamespace {
+		log.Errorf("Did not recognize broker scope [%v]. Acceptable values: 'auto', 'cluster', 'namespace'", scope)
+		return "", "", false
+	}
+
+	namespace, ok := getCatalogNamespace()
+	if !ok {
+		if scope == servicecatalog.BrokerScopeNamespace {
+			return "", "", false
+		}
+		log.Debugf("No namespace to search for a servicebroker, using clusterservicebroker [%v]", brokerName)
+		return clusterPath, "clusterservicebroker", true
 	}
-	http.DefaultClient.Transport = &http.Transport{
-		TLSClientConfig: cfg,
+	namespacedPath := servicecatalog.NamespacedBrokerPath(namespace, brokerName)
+	if scope == servicecatalog.BrokerScopeNamespace {
+		return namespacedPath, "servicebroker", true
 	}
-	resp, err := http.DefaultClient.Do(req)
+
+	detected, err := servicecatalog.DetectBrokerScope(clusterPath, namespacedPath)
 	if err != nil {
-		log.Errorf("Failed to get relist response: %v", err)
+		if kapierrors.IsNotFound(err) {
+			log.Errorf("Failed to find clusterservicebroker [%v] or servicebroker [%v] in namespace [%v]. Try specifying name with --name flag.", brokerName, brokerName, namespace)
+			return "", "", false
+		}
+		log.Errorf("Failed to detect broker scope: %v. Try specifying it with --scope.", err)
+		return "", "", false
 	}
-	defer resp.Body.Close()
+	log.Debugf("Detected broker scope [%v] for broker [%v]", detected, brokerName)
+	if detected == servicecatalog.BrokerScopeNamespace {
+		return namespacedPath, "servicebroker", true
+	}
+	return clusterPath, "clusterservicebroker", true
+}
+
+func handleRelistErr(brokerKind string, brokerPath string, err error) {
 	// Special case for 404 to tell user about --name flag
-	if resp.StatusCode == 404 {
-		log.Errorf("Failed to find clusterservicebroker resource [%v]. Try specifying name with --name flag.", brokerURL)
+	if kapierrors.IsNotFound(err) {
+		log.Errorf("Failed to find %v resource [%v]. Try specifying name with --name flag.", brokerKind, brokerPath)
+		return
+	}
+	log.Errorf("Failed to relist broker [%v]: %v", brokerPath, err)
+	if kapierrors.IsForbidden(err) {
+		handleResourceInaccessibleErr(brokerKind+"s", "", true)
+	}
+}
+
+func listCatalogClasses() {
+	classes, err := servicecatalog.ListClusterServiceClasses(brokerResourceName)
+	if err != nil {
+		handleCatalogListErr("clusterserviceclasses", err)
+		return
+	}
+	if len(classes) == 0 {
+		fmt.Println("Found no clusterserviceclasses")
 		return
 	}
-	if resp.StatusCode != 200 {
-		respBody, err := ioutil.ReadAll(resp.Body)
+
+	colName := &util.TableColumn{Header: "NAME"}
+	colID := &util.TableColumn{Header: "ID"}
+	colBroker := &util.TableColumn{Header: "BROKER"}
+	colBind := &util.TableColumn{Header: "BINDABLE"}
+	colStatus := &util.TableColumn{Header: "STATUS"}
+
+	for _, c := range classes {
+		colName.Data = append(colName.Data, c.Spec.ExternalName)
+		colID.Data = append(colID.Data, c.Name)
+		colBroker.Data = append(colBroker.Data, c.Spec.ClusterServiceBrokerName)
+		colBind.Data = append(colBind.Data, strconv.FormatBool(c.Spec.Bindable))
+		colStatus.Data = append(colStatus.Data, catalogEntryStatus(c.Status.RemovedFromBrokerCatalog))
+	}
+
+	tableToPrint := []*util.TableColumn{colName, colID, colBroker, colBind, colStatus}
+	util.PrintTable(tableToPrint)
+}
+
+func listCatalogPlans(className string) {
+	var class *servicecatalog.ClusterServiceClass
+	var err error
+	if className != "" {
+		class, err = servicecatalog.GetClusterServiceClass(className)
 		if err != nil {
-			log.Errorf("Failed to read relist response body: %v", err)
+			log.Errorf("Failed to get clusterserviceclass [%v]: %v", className, err)
 			return
 		}
-		log.Errorf("Bad relist response. Expected status 200, got: %v\n%s", resp.StatusCode, respBody)
-		if bytes.Contains(respBody, []byte("cannot get clusterservicebrokers")) {
-			handleResourceInaccessibleErr("clusterservicebrokers", "", true)
+	}
+	plans, err := servicecatalog.ListClusterServicePlans(brokerResourceName)
+	if err != nil {
+		handleCatalogListErr("clusterserviceplans", err)
+		return
+	}
+	classMap := getCatalogClasses()
+
+	colName := &util.TableColumn{Header: "NAME"}
+	colClass := &util.TableColumn{Header: "CLASS"}
+	colID := &util.TableColumn{Header: "ID"}
+	colBroker := &util.TableColumn{Header: "BROKER"}
+	colFree := &util.TableColumn{Header: "FREE"}
+	colStatus := &util.TableColumn{Header: "STATUS"}
+
+	for _, p := range plans {
+		if class != nil && p.Spec.ClusterServiceClassRef.Name != class.Name {
+			continue
 		}
+		colName.Data = append(colName.Data, p.Spec.ExternalName)
+		colClass.Data = append(colClass.Data, classMap[p.Spec.ClusterServiceClassRef.Name].Spec.ExternalName)
+		colID.Data = append(colID.Data, p.Name)
+		colBroker.Data = append(colBroker.Data, p.Spec.ClusterServiceBrokerName)
+		colFree.Data = append(colFree.Data, strconv.FormatBool(p.Spec.Free))
+		colStatus.Data = append(colStatus.Data, catalogEntryStatus(p.Status.RemovedFromBrokerCatalog))
+	}
+	if len(colName.Data) == 0 {
+		fmt.Println("Found no clusterserviceplans")
 		return
 	}
-	// Read response and unmarshal to get relistRequest count
-	jsonRelist, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Errorf("Failed to read relist response body: %v", err)
+
+	tableToPrint := []*util.TableColumn{colName, colClass, colID, colBroker, colFree, colStatus}
+	util.PrintTable(tableToPrint)
+}
+
+func listCatalogInstances() {
+	namespace, ok := getCatalogNamespace()
+	if !ok {
 		return
 	}
-	relistResp := relistResponse{}
-	err = json.Unmarshal(jsonRelist, &relistResp)
+	instances, err := servicecatalog.ListServiceInstances(namespace)
 	if err != nil {
-		log.Errorf("Failed to unmarshal relist response: %v", err)
+		handleCatalogListErr("serviceinstances", err)
 		return
 	}
-	// Increment relist requests and PATCH clusterservicebroker resource
-	newRelistCount := relistResp.Spec.RelistRequests + 1
-	var patchRequest = []byte(fmt.Sprintf("{\"spec\": {\"relistRequests\": %v}}", newRelistCount))
-	req, err = http.NewRequest("PATCH", brokerURL, bytes.NewBuffer(patchRequest))
-	if err != nil {
-		log.Errorf("Failed to create patch relist request: %v", err)
+	if len(instances) == 0 {
+		fmt.Println("Found no serviceinstances")
 		return
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", kube.ClientConfig.BearerToken))
-	req.Header.Set("Content-Type", "application/strategic-merge-patch+json")
-	resp, err = http.DefaultClient.Do(req)
+	classMap := getCatalogClasses()
+
+	colNamespace := &util.TableColumn{Header: "NAMESPACE"}
+	colName := &util.TableColumn{Header: "NAME"}
+	colClass := &util.TableColumn{Header: "CLASS"}
+	colPlan := &util.TableColumn{Header: "PLAN"}
+	colBroker := &util.TableColumn{Header: "BROKER"}
+	colProvision := &util.TableColumn{Header: "PROVISIONED"}
+	colStatus := &util.TableColumn{Header: "STATUS"}
+	colLastOp := &util.TableColumn{Header: "LAST OPERATION"}
+
+	for _, i := range instances {
+		broker := ""
+		if i.Spec.ClusterServiceClassRef != nil {
+			broker = classMap[i.Spec.ClusterServiceClassRef.Name].Spec.ClusterServiceBrokerName
+		}
+		colNamespace.Data = append(colNamespace.Data, i.Namespace)
+		colName.Data = append(colName.Data, i.Name)
+		colClass.Data = append(colClass.Data, i.Spec.ClusterServiceClassExternalName)
+		colPlan.Data = append(colPlan.Data, i.Spec.ClusterServicePlanExternalName)
+		colBroker.Data = append(colBroker.Data, broker)
+		colProvision.Data = append(colProvision.Data, i.Status.ProvisionStatus)
+		colStatus.Data = append(colStatus.Data, conditionStatus(i.Status.Conditions))
+		colLastOp.Data = append(colLastOp.Data, lastOperation(i.Status.CurrentOperation, i.Status.LastConditionState))
+	}
+
+	tableToPrint := []*util.TableColumn{colNamespace, colName, colClass, colPlan, colBroker, colProvision, colStatus, colLastOp}
+	util.PrintTable(tableToPrint)
+}
+
+func listCatalogBindings() {
+	namespace, ok := getCatalogNamespace()
+	if !ok {
+		return
+	}
+	bindings, err := servicecatalog.ListServiceBindings(namespace)
 	if err != nil {
-		log.Errorf("Failed to send PATCH relist request: %v", err)
+		handleCatalogListErr("servicebindings", err)
 		return
 	}
-	if resp.StatusCode != 200 {
-		log.Errorf("Error: Relist status code is not 200, got: %v", resp.Status)
+	if len(bindings) == 0 {
+		fmt.Println("Found no servicebindings")
 		return
 	}
-	fmt.Printf("Successfully relisted OpenShift Service Catalog for [%v]\n", clusterServiceBrokerName)
-	return
+
+	colNamespace := &util.TableColumn{Header: "NAMESPACE"}
+	colName := &util.TableColumn{Header: "NAME"}
+	colInstance := &util.TableColumn{Header: "INSTANCE"}
+	colSecret := &util.TableColumn{Header: "SECRET"}
+	colStatus := &util.TableColumn{Header: "STATUS"}
+	colLastOp := &util.TableColumn{Header: "LAST OPERATION"}
+
+	for _, b := range bindings {
+		colNamespace.Data = append(colNamespace.Data, b.Namespace)
+		colName.Data = append(colName.Data, b.Name)
+		colInstance.Data = append(colInstance.Data, b.Spec.InstanceRef.Name)
+		colSecret.Data = append(colSecret.Data, b.Spec.SecretName)
+		colStatus.Data = append(colStatus.Data, conditionStatus(b.Status.Conditions))
+		colLastOp.Data = append(colLastOp.Data, lastOperation(b.Status.CurrentOperation, b.Status.LastConditionState))
+	}
+
+	tableToPrint := []*util.TableColumn{colNamespace, colName, colInstance, colSecret, colStatus, colLastOp}
+	util.PrintTable(tableToPrint)
+}
+
+func describeCatalogObject(kind string, name string) {
+	switch kind {
+	case "class":
+		class, err := servicecatalog.GetClusterServiceClass(name)
+		if err != nil {
+			log.Errorf("Failed to get clusterserviceclass [%v]: %v", name, err)
+			return
+		}
+		printCatalogField("NAME", class.Spec.ExternalName)
+		printCatalogField("ID", class.Name)
+		printCatalogField("DESCRIPTION", class.Spec.Description)
+		printCatalogField("BROKER", class.Spec.ClusterServiceBrokerName)
+		printCatalogField("BINDABLE", class.Spec.Bindable)
+		printCatalogField("UPDATABLE", class.Spec.PlanUpdatable)
+		printCatalogField("STATUS", catalogEntryStatus(class.Status.RemovedFromBrokerCatalog))
+		plans, err := servicecatalog.ListClusterServicePlans(class.Spec.ClusterServiceBrokerName)
+		if err != nil {
+			log.Errorf("Failed to list clusterserviceplans: %v", err)
+			return
+		}
+		for _, p := range plans {
+			if p.Spec.ClusterServiceClassRef.Name == class.Name {
+				printCatalogField("PLAN", fmt.Sprintf("%v (%v)", p.Spec.ExternalName, p.Name))
+			}
+		}
+	case "plan":
+		plan, err := servicecatalog.GetClusterServicePlan(name)
+		if err != nil {
+			log.Errorf("Failed to get clusterserviceplan [%v]: %v", name, err)
+			return
+		}
+		printCatalogField("NAME", plan.Spec.ExternalName)
+		printCatalogField("ID", plan.Name)
+		printCatalogField("DESCRIPTION", plan.Spec.Description)
+		printCatalogField("CLASS", plan.Spec.ClusterServiceClassRef.Name)
+		printCatalogField("BROKER", plan.Spec.ClusterServiceBrokerName)
+		printCatalogField("FREE", plan.Spec.Free)
+		printCatalogField("STATUS", catalogEntryStatus(plan.Status.RemovedFromBrokerCatalog))
+	case "instance":
+		namespace, ok := getCatalogNamespace()
+		if !ok {
+			return
+		}
+		instance, err := servicecatalog.GetServiceInstance(namespace, name)
+		if err != nil {
+			log.Errorf("Failed to get serviceinstance [%v] in namespace [%v]: %v", name, namespace, err)
+			return
+		}
+		printCatalogField("NAME", instance.Name)
+		printCatalogField("NAMESPACE", instance.Namespace)
+		printCatalogField("ID", instance.Spec.ExternalID)
+		printCatalogField("CLASS", instance.Spec.ClusterServiceClassExternalName)
+		printCatalogField("PLAN", instance.Spec.ClusterServicePlanExternalName)
+		if instance.Spec.ClusterServiceClassRef != nil {
+			printCatalogField("BROKER", getCatalogClasses()[instance.Spec.ClusterServiceClassRef.Name].Spec.ClusterServiceBrokerName)
+		}
+		printCatalogField("PROVISIONED", instance.Status.ProvisionStatus)
+		printCatalogField("DEPROVISION", instance.Status.DeprovisionStatus)
+		printCatalogField("LAST OP", lastOperation(instance.Status.CurrentOperation, instance.Status.LastConditionState))
+		if instance.Status.DashboardURL != nil {
+			printCatalogField("DASHBOARD", *instance.Status.DashboardURL)
+		}
+		printConditions(instance.Status.Conditions)
+	case "binding":
+		namespace, ok := getCatalogNamespace()
+		if !ok {
+			return
+		}
+		binding, err := servicecatalog.GetServiceBinding(namespace, name)
+		if err != nil {
+			log.Errorf("Failed to get servicebinding [%v] in namespace [%v]: %v", name, namespace, err)
+			return
+		}
+		printCatalogField("NAME", binding.Name)
+		printCatalogField("NAMESPACE", binding.Namespace)
+		printCatalogField("ID", binding.Spec.ExternalID)
+		printCatalogField("INSTANCE", binding.Spec.InstanceRef.Name)
+		printCatalogField("SECRET", binding.Spec.SecretName)
+		printCatalogField("UNBIND", binding.Status.UnbindStatus)
+		printCatalogField("LAST OP", lastOperation(binding.Status.CurrentOperation, binding.Status.LastConditionState))
+		printConditions(binding.Status.Conditions)
+	default:
+		log.Errorf("Unknown object type [%v]. Expected one of: class, plan, instance, binding", kind)
+	}
+}
+
+func printCatalogField(name string, value interface{}) {
+	fmt.Printf(" %-11s  |  %v\n", name, value)
+}
+
+func printConditions(conditions []servicecatalog.Condition) {
+	if len(conditions) == 0 {
+		return
+	}
+	fmt.Println()
+	colType := &util.TableColumn{Header: "CONDITION"}
+	colStatus := &util.TableColumn{Header: "STATUS"}
+	colTime := &util.TableColumn{Header: "LAST TRANSITION"}
+	colReason := &util.TableColumn{Header: "REASON"}
+	colMessage := &util.TableColumn{Header: "MESSAGE"}
+
+	for _, c := range conditions {
+		colType.Data = append(colType.Data, c.Type)
+		colStatus.Data = append(colStatus.Data, c.Status)
+		colTime.Data = append(colTime.Data, c.LastTransitionTime.String())
+		colReason.Data = append(colReason.Data, c.Reason)
+		colMessage.Data = append(colMessage.Data, c.Message)
+	}
+
+	tableToPrint := []*util.TableColumn{colType, colStatus, colTime, colReason, colMessage}
+	util.PrintTable(tableToPrint)
+}
+
+// Namespace for instance and binding commands. Empty namespace means all namespaces.
+func getCatalogNamespace() (string, bool) {
+	if catalogAllNamespaces {
+		return "", true
+	}
+	if catalogNamespace == "" {
+		catalogNamespace = util.GetCurrentNamespace(kubeConfig)
+		if catalogNamespace == "" {
+			log.Errorf("Failed to get current namespace. Try supplying it with --namespace.")
+			return "", false
+		}
+	}
+	return catalogNamespace, true
+}
+
+// Map of ClusterServiceClass object names to classes, used to resolve class references
+func getCatalogClasses() map[string]servicecatalog.ClusterServiceClass {
+	classMap := map[string]servicecatalog.ClusterServiceClass{}
+	classes, err := servicecatalog.ListClusterServiceClasses("")
+	if err != nil {
+		log.Debugf("Failed to list clusterserviceclasses: %v", err)
+		return classMap
+	}
+	for _, c := range classes {
+		classMap[c.Name] = c
+	}
+	return classMap
+}
+
+func catalogEntryStatus(removedFromBrokerCatalog bool) string {
+	if removedFromBrokerCatalog {
+		return "removed from broker catalog"
+	}
+	return "active"
+}
+
+// Summarize the Ready condition as its reason, or its status when no reason is given
+func conditionStatus(conditions []servicecatalog.Condition) string {
+	ready := servicecatalog.GetCondition(conditions, "Ready")
+	if ready == nil {
+		return "Unknown"
+	}
+	if ready.Reason != "" {
+		return ready.Reason
+	}
+	return fmt.Sprintf("Ready=%v", ready.Status)
+}
+
+func lastOperation(currentOperation string, lastConditionState string) string {
+	if currentOperation != "" {
+		return fmt.Sprintf("%v (in progress)", currentOperation)
+	}
+	return lastConditionState
+}
+
+func handleCatalogListErr(resourceType string, err error) {
+	log.Errorf("Failed to list %v: %v", resourceType, err)
+	if strings.Contains(err.Error(), "cannot list "+resourceType) {
+		handleResourceInaccessibleErr(resourceType, "", true)
+	}
 }
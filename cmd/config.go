@@ -43,6 +43,15 @@ func gatherDefaultsConfig() {
 		BrokerRouteName:          getUserInput("Broker route name", config.InitialDefaultSettings().BrokerRouteName),
 		ClusterServiceBrokerName: getUserInput("clusterservicebroker resource name", config.InitialDefaultSettings().ClusterServiceBrokerName),
 		BrokerRouteSuffix:        getUserInput("Broker route suffix", config.InitialDefaultSettings().BrokerRouteSuffix),
+		BrokerScope:              getUserInput("Broker scope (auto, cluster or namespace)", config.InitialDefaultSettings().BrokerScope),
+		Proxy: config.ProxySettings{
+			HTTPProxy:  getUserInput("HTTP proxy for APB pods", config.LoadedDefaults.Proxy.HTTPProxy),
+			HTTPSProxy: getUserInput("HTTPS proxy for APB pods", config.LoadedDefaults.Proxy.HTTPSProxy),
+			NoProxy:    getUserInput("Hosts APB pods reach without proxy", config.LoadedDefaults.Proxy.NoProxy),
+		},
+		StateNamespace: getUserInput("Namespace keeping the state of APB instances (empty for the instance namespace)", config.LoadedDefaults.StateNamespace),
+		// Pod settings are edited in defaults.json, keep them
+		Pod: config.LoadedDefaults.Pod,
 	}
 	fmt.Println("\nSaving new configuration....")
 	config.UpdateCachedDefaults(config.Defaults, defaultSettings)
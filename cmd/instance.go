@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/automationbroker/apb/pkg/runner"
+	"github.com/automationbroker/apb/pkg/util"
+	"github.com/spf13/cobra"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var instanceNamespace string
+
+var instanceCmd = &cobra.Command{
+	Use:   "instance",
+	Short: "Inspect provisioned APB instances",
+	Long:  `Inspect APB instances provisioned with 'apb bundle provision'`,
+}
+
+var instanceStateCmd = &cobra.Command{
+	Use:   "state <instance-id>",
+	Short: "Show the state of an instance",
+	Long:  `Show the state saved by the APBs of an instance, which is passed to their later actions`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showInstanceState(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(instanceCmd)
+	instanceStateCmd.Flags().StringVarP(&instanceNamespace, "namespace", "n", "", "Namespace of the instance")
+	addStateFlags(instanceStateCmd.Flags())
+	instanceCmd.AddCommand(instanceStateCmd)
+}
+
+func showInstanceState(instanceID string) {
+	namespace := instanceNamespace
+	if namespace == "" {
+		namespace = util.GetCurrentNamespace(kubeConfig)
+	}
+	state, err := runner.InstanceState(namespace, stateNamespaceSetting(), instanceID)
+	if err != nil {
+		log.Errorf("Failed to read the state of instance [%v]: %v", instanceID, err)
+		return
+	}
+	if len(state) == 0 {
+		fmt.Printf("No state saved for instance [%v]\n", instanceID)
+		return
+	}
+	keys := []string{}
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("%v: %v\n", key, state[key])
+	}
+}
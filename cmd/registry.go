@@ -71,7 +71,7 @@ var registryAddCmd = &cobra.Command{
 	Use:   "add <registry_name>",
 	Short: "Add a new registry adapter",
 	Long:  `Add a new registry adapter to the configuration`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		addRegistry(args[0])
 	},
@@ -80,7 +80,7 @@ var registryAddCmd = &cobra.Command{
 var registryRemoveCmd = &cobra.Command{
 	Use:   "remove <registry_name>",
 	Short: "Remove a registry adapter",
-	Args:  cobra.MinimumNArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Long:  `Remove a registry adapter from stored configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
 		removeRegistry(args[0])
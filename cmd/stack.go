@@ -0,0 +1,113 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cmd
+
+import (
+	"github.com/automationbroker/apb/pkg/runner"
+	"github.com/automationbroker/apb/pkg/stack"
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var stackFile string
+var stackNamespace string
+
+var stackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "Provision and deprovision stacks of APBs",
+	Long:  `Provision and deprovision several APBs declared in a stack manifest`,
+}
+
+var stackUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Provision the APBs of a stack",
+	Long: `Provision the APBs of a stack in dependency order, running independent APBs in parallel.
+Credentials of bound APBs are passed as parameters to the APBs binding to them. APBs already provisioned are kept.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStack(stack.Up)
+	},
+}
+
+var stackDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Deprovision the APBs of a stack",
+	Long:  `Deprovision every instance of the APBs of a stack, in reverse dependency order`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStack(stack.Down)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stackCmd)
+	stackCmd.PersistentFlags().StringVarP(&stackFile, "file", "f", "stack.yml", "Stack manifest")
+	stackCmd.PersistentFlags().StringVarP(&stackNamespace, "namespace", "n", "", "Namespace of the APBs that don't declare one, instead of the current namespace")
+	stackCmd.PersistentFlags().StringVarP(&sandboxRole, "sandbox-role", "s", "edit", "ClusterRole to be applied to APB sandboxes")
+	addStateFlags(stackCmd.PersistentFlags())
+
+	for _, cmd := range []*cobra.Command{stackUpCmd, stackDownCmd} {
+		cmd.Flags().BoolVar(&inlineExtraVars, "inline-extra-vars", false, "Pass extra-vars as a pod argument instead of through a secret, for images based on older apb-base")
+		addPodFlags(cmd)
+		addEnvFlags(cmd)
+		stackCmd.AddCommand(cmd)
+	}
+}
+
+// Load the stack manifest and bring it up or down
+func runStack(run func(*stack.Stack, stack.Options) error) {
+	namespace := stackNamespace
+	if namespace == "" {
+		namespace = util.GetCurrentNamespace(kubeConfig)
+	}
+	s, err := stack.Load(stackFile, namespace)
+	if err != nil {
+		log.Errorf("Failed to load stack: %v", err)
+		return
+	}
+	opts := stack.Options{
+		SandboxRole: sandboxRole,
+		Run: runner.RunOptions{
+			InlineExtraVars:   inlineExtraVars,
+			Pod:               podSettings(),
+			Env:               podEnv,
+			EnvFromSecrets:    podEnvFromSecrets,
+			EnvFromConfigMaps: podEnvFromConfigMaps,
+			Proxy:             proxySettings(),
+			StateNamespace:    stateNamespaceSetting(),
+		},
+		Recorded: recordStackInstance,
+	}
+	err = run(s, opts)
+	if err != nil {
+		log.Errorf("Stack [%v]: %v", s.Name, err)
+	}
+}
+
+// Keep the list of provisioned instances up to date
+func recordStackInstance(action string, entry stack.Entry, instanceID string) {
+	var err error
+	if action == "provision" {
+		err = addInstance(entry.Bundle, entry.Namespace, instanceID, nil, "")
+	} else {
+		err = removeInstance(entry.Bundle, entry.Namespace, instanceID)
+	}
+	if err != nil {
+		log.Errorf("Failed to update the list of provisioned instances: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package binding
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BindingLabel marks the secrets created by 'apb binding add'
+const BindingLabel = "apb.automationbroker.io/binding"
+
+// SourceAnnotation records the APB secret a binding secret was created from
+const SourceAnnotation = "apb.automationbroker.io/binding-source"
+
+// Consumer is a workload whose pod template references a secret
+type Consumer struct {
+	Workload Workload
+	// Injected is true when the reference was added by 'apb binding add --inject'
+	Injected bool
+}
+
+// FindConsumers returns the workloads in a namespace keyed by the secrets their pod templates reference.
+// Workload kinds the cluster does not serve, e.g. DeploymentConfigs on Kubernetes, are skipped.
+func FindConsumers(namespace string) (map[string][]Consumer, error) {
+	consumers := map[string][]Consumer{}
+	for _, k := range workloadKinds {
+		list, err := util.ListResources(fmt.Sprintf("%v/namespaces/%v/%v", k.apiPath, namespace, k.resource))
+		if kapierrors.IsNotFound(err) {
+			log.Debugf("Cluster does not serve %v, skipping", k.resource)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			spec, err := getPodSpec(obj, k.templatePath)
+			if err != nil {
+				log.Debugf("Skipping %v [%v]: %v", k.kind, obj.GetName(), err)
+				continue
+			}
+			injections, err := getInjections(obj)
+			if err != nil {
+				log.Warningf("Ignoring injections recorded on %v: %v", k.kind, err)
+			}
+			injected := map[string]bool{}
+			for _, injection := range injections {
+				injected[injection.Secret] = true
+			}
+			for _, secret := range secretRefs(spec) {
+				consumers[secret] = append(consumers[secret], Consumer{
+					Workload: Workload{Kind: k.kind, Name: obj.GetName()},
+					Injected: injected[secret],
+				})
+			}
+		}
+	}
+	return consumers, nil
+}
+
+func getPodSpec(obj *unstructured.Unstructured, templatePath []string) (*v1.PodSpec, error) {
+	templateMap, ok := unstructured.NestedMap(obj.Object, templatePath...)
+	if !ok {
+		return nil, fmt.Errorf("%v [%v] has no pod template", obj.GetKind(), obj.GetName())
+	}
+	template := &v1.PodTemplateSpec{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(templateMap, template)
+	if err != nil {
+		return nil, err
+	}
+	return &template.Spec, nil
+}
+
+// Names of the secrets a pod spec references through volumes, envFrom or env, in order of first reference
+func secretRefs(spec *v1.PodSpec) []string {
+	refs := []string{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+	for _, v := range spec.Volumes {
+		if v.Secret != nil {
+			add(v.Secret.SecretName)
+		}
+	}
+	containers := append([]v1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, c := range containers {
+		for _, e := range c.EnvFrom {
+			if e.SecretRef != nil {
+				add(e.SecretRef.Name)
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				add(e.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	return refs
+}
@@ -0,0 +1,72 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package binding
+
+import (
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels recording where a binding secret copied across namespaces came from. Owner references can't
+// point across namespaces, so these let the copies be found and cleaned up.
+const (
+	SourceNamespaceLabel = "apb.automationbroker.io/source-namespace"
+	SourceSecretLabel    = "apb.automationbroker.io/source-secret"
+)
+
+// CopyToNamespace copies a binding secret to another namespace, updating an earlier copy in place so
+// workloads consuming it never see it missing. The runtime's CopySecretsToNamespace only creates
+// secrets and fails on an existing copy, so the copy is saved with SaveSecret instead.
+func CopyToNamespace(secretName string, fromNamespace string, toNamespace string) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	secret, err := k8scli.Client.CoreV1().Secrets(fromNamespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	secret.ObjectMeta = metav1.ObjectMeta{
+		Name:        secret.Name,
+		Namespace:   toNamespace,
+		Labels:      secret.Labels,
+		Annotations: secret.Annotations,
+	}
+	return SaveSecret(toNamespace, secret)
+}
+
+// SaveSecret creates a secret, or updates it in place when it already exists
+func SaveSecret(namespace string, secret *v1.Secret) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	secrets := k8scli.Client.CoreV1().Secrets(namespace)
+	_, err = secrets.Create(secret)
+	if !kapierrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := secrets.Get(secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	secret.ResourceVersion = existing.ResourceVersion
+	_, err = secrets.Update(secret)
+	return err
+}
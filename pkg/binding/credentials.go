@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/clients"
+)
+
+// Secret layouts for the binding credentials
+const (
+	// FormatEnv stores one key per credential, named so it can be consumed as an environment variable
+	FormatEnv = "env"
+	// FormatFile stores one key per credential under its original name, for mounting as files
+	FormatFile = "file"
+	// FormatJSON stores all credentials as a single JSON document under JSONKey
+	FormatJSON = "json"
+)
+
+// JSONKey is the secret key holding the credentials in the json format
+const JSONKey = "credentials.json"
+
+var secretKeyRegexp = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+var invalidEnvCharRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// CredentialOptions controls which extracted credentials end up in a binding secret and how
+type CredentialOptions struct {
+	// Only keeps just these credentials when not empty
+	Only []string
+	// Mapping renames credentials, from the extracted name to the secret key
+	Mapping map[string]string
+	// Prefix is prepended to every secret key
+	Prefix string
+	// Format is one of FormatEnv, FormatFile or FormatJSON
+	Format string
+}
+
+// ExtractCredentials reads the credentials an APB extracted into the secret named after its pod
+func ExtractCredentials(secretName string, namespace string) (map[string]interface{}, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve kubernetes client - [%v]", err)
+	}
+	secret, err := k8s.GetSecretData(secretName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve secret [%v] - [%v]", secretName, err)
+	}
+	return decodeCredentials(secret["fields"])
+}
+
+// Keep numbers as written by the APB instead of converting them to floats
+func decodeCredentials(data []byte) (map[string]interface{}, error) {
+	creds := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&creds)
+	if err != nil {
+		return nil, fmt.Errorf("Unexpected error building extracted creds: [%v]", err)
+	}
+	return creds, nil
+}
+
+// ParseMapping parses 'src=DEST' key renames
+func ParseMapping(mappings []string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, m := range mappings {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid key mapping [%v], expected src=DEST", m)
+		}
+		mapping[parts[0]] = parts[1]
+	}
+	return mapping, nil
+}
+
+// BuildSecretData lays out extracted credentials as secret data. Scalar values are stored as is,
+// nested objects and lists are JSON encoded.
+func BuildSecretData(creds map[string]interface{}, opts CredentialOptions) (map[string][]byte, error) {
+	for _, key := range opts.Only {
+		if _, ok := creds[key]; !ok {
+			return nil, fmt.Errorf("credential [%v] not found. Available credentials: %v", key, strings.Join(credentialNames(creds), ", "))
+		}
+	}
+	for key := range opts.Mapping {
+		if _, ok := creds[key]; !ok {
+			return nil, fmt.Errorf("can not map credential [%v], it was not found", key)
+		}
+	}
+
+	selected := map[string]interface{}{}
+	for key, value := range creds {
+		if len(opts.Only) > 0 && !contains(opts.Only, key) {
+			continue
+		}
+		if dest, ok := opts.Mapping[key]; ok {
+			key = dest
+		}
+		key = opts.Prefix + key
+		if opts.Format == FormatEnv {
+			key = invalidEnvCharRegexp.ReplaceAllString(key, "_")
+		}
+		if _, ok := selected[key]; ok {
+			return nil, fmt.Errorf("more than one credential maps to key [%v]", key)
+		}
+		selected[key] = value
+	}
+
+	data := map[string][]byte{}
+	switch opts.Format {
+	case FormatJSON:
+		d, err := json.Marshal(selected)
+		if err != nil {
+			return nil, err
+		}
+		data[JSONKey] = d
+	case FormatEnv, FormatFile, "":
+		for key, value := range selected {
+			if !secretKeyRegexp.MatchString(key) {
+				return nil, fmt.Errorf("[%v] is not a valid secret key. Rename it with --map", key)
+			}
+			d, err := FormatCredential(value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode credential [%v]: %v", key, err)
+			}
+			data[key] = d
+		}
+	default:
+		return nil, fmt.Errorf("unknown format [%v], expected %v, %v or %v", opts.Format, FormatEnv, FormatFile, FormatJSON)
+	}
+	return data, nil
+}
+
+// FormatCredential stores scalars raw so applications read them unchanged, anything else as JSON
+func FormatCredential(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case json.Number:
+		return []byte(v.String()), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		return []byte(strconv.FormatBool(v)), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func credentialNames(creds map[string]interface{}) []string {
+	names := []string{}
+	for key := range creds {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
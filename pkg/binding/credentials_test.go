@@ -0,0 +1,91 @@
+package binding
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildSecretData(t *testing.T) {
+	creds := map[string]interface{}{
+		"db-host":  "postgres",
+		"password": "secret",
+		"port":     json.Number("5432"),
+		"ssl":      true,
+		"replicas": []interface{}{"a", "b"},
+	}
+	// test case table
+	testCases := []struct {
+		name      string
+		opts      CredentialOptions
+		expected  map[string]string
+		shouldErr bool
+	}{
+		{
+			name: "test scalars stored raw",
+			opts: CredentialOptions{Format: FormatFile, Only: []string{"password", "port", "ssl", "replicas"}},
+			expected: map[string]string{
+				"password": "secret",
+				"port":     "5432",
+				"ssl":      "true",
+				"replicas": `["a","b"]`,
+			},
+		},
+		{
+			name: "test env format with prefix and mapping",
+			opts: CredentialOptions{
+				Format:  FormatEnv,
+				Only:    []string{"db-host", "password"},
+				Mapping: map[string]string{"password": "PASS"},
+				Prefix:  "PG_",
+			},
+			expected: map[string]string{
+				"PG_db_host": "postgres",
+				"PG_PASS":    "secret",
+			},
+		},
+		{
+			name: "test json format",
+			opts: CredentialOptions{Format: FormatJSON, Only: []string{"password", "port"}},
+			expected: map[string]string{
+				JSONKey: `{"password":"secret","port":5432}`,
+			},
+		},
+		{
+			name:      "test unknown only key",
+			opts:      CredentialOptions{Only: []string{"user"}},
+			shouldErr: true,
+		},
+		{
+			name:      "test colliding mapping",
+			opts:      CredentialOptions{Mapping: map[string]string{"ssl": "password"}},
+			shouldErr: true,
+		},
+		{
+			name:      "test unknown format",
+			opts:      CredentialOptions{Format: "yaml"},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := BuildSecretData(creds, tc.opts)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(data) != len(tc.expected) {
+				t.Fatalf("expected %v keys, got %v", len(tc.expected), data)
+			}
+			for key, value := range tc.expected {
+				if string(data[key]) != value {
+					t.Fatalf("expected [%v] to be [%v], got [%v]", key, value, string(data[key]))
+				}
+			}
+		})
+	}
+}
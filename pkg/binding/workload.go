@@ -0,0 +1,301 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package binding
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/util"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BindingsAnnotation records the binding secrets apb injected into a workload, so they can be removed later
+const BindingsAnnotation = "apb.automationbroker.io/bindings"
+
+// Name prefix of the volumes apb adds to mount binding secrets
+const volumePrefix = "apb-binding-"
+
+// Injection describes how a binding secret is attached to the containers of a workload
+type Injection struct {
+	Secret    string `json:"secret"`
+	EnvPrefix string `json:"envPrefix,omitempty"`
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// Workload identifies a Deployment, DeploymentConfig, StatefulSet or CronJob
+type Workload struct {
+	Kind string
+	Name string
+}
+
+// workloadKind maps a workload kind to its API location and the path of its pod template
+type workloadKind struct {
+	kind         string
+	shortName    string
+	apiPath      string
+	resource     string
+	templatePath []string
+}
+
+var workloadKinds = []workloadKind{
+	{
+		kind:         "DeploymentConfig",
+		shortName:    "dc",
+		apiPath:      "/apis/apps.openshift.io/v1",
+		resource:     "deploymentconfigs",
+		templatePath: []string{"spec", "template"},
+	},
+	{
+		kind:         "Deployment",
+		shortName:    "deploy",
+		apiPath:      "/apis/apps/v1",
+		resource:     "deployments",
+		templatePath: []string{"spec", "template"},
+	},
+	{
+		kind:         "StatefulSet",
+		shortName:    "sts",
+		apiPath:      "/apis/apps/v1",
+		resource:     "statefulsets",
+		templatePath: []string{"spec", "template"},
+	},
+	{
+		kind:         "CronJob",
+		shortName:    "cj",
+		apiPath:      "/apis/batch/v1beta1",
+		resource:     "cronjobs",
+		templatePath: []string{"spec", "jobTemplate", "spec", "template"},
+	},
+}
+
+// ParseWorkload parses a workload reference such as 'dc/myapp' or 'deployment/myapp'.
+// A bare name refers to a DeploymentConfig.
+func ParseWorkload(ref string) (Workload, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) == 1 {
+		return Workload{Kind: "DeploymentConfig", Name: ref}, nil
+	}
+	if parts[1] == "" {
+		return Workload{}, fmt.Errorf("missing workload name in [%v]", ref)
+	}
+	kindName := strings.ToLower(parts[0])
+	for _, k := range workloadKinds {
+		if kindName == k.shortName || kindName == strings.ToLower(k.kind) || kindName == k.resource {
+			return Workload{Kind: k.kind, Name: parts[1]}, nil
+		}
+	}
+	return Workload{}, fmt.Errorf("unsupported workload kind [%v]. Supported kinds: dc, deployment, statefulset, cronjob", parts[0])
+}
+
+// String returns the workload in the short form accepted by 'oc', e.g. 'dc/myapp'
+func (w Workload) String() string {
+	return fmt.Sprintf("%v/%v", getWorkloadKind(w.Kind).shortName, w.Name)
+}
+
+// APIGroup returns the API group serving the workload, empty for the core group
+func (w Workload) APIGroup() string {
+	parts := strings.Split(strings.TrimPrefix(getWorkloadKind(w.Kind).apiPath, "/apis/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// Resource returns the plural resource name of the workload kind, e.g. 'deployments'
+func (w Workload) Resource() string {
+	return getWorkloadKind(w.Kind).resource
+}
+
+func getWorkloadKind(kind string) workloadKind {
+	for _, k := range workloadKinds {
+		if k.kind == kind {
+			return k
+		}
+	}
+	return workloadKind{kind: kind, shortName: strings.ToLower(kind)}
+}
+
+func workloadPath(namespace string, w Workload) string {
+	k := getWorkloadKind(w.Kind)
+	return fmt.Sprintf("%v/namespaces/%v/%v/%v", k.apiPath, namespace, k.resource, w.Name)
+}
+
+// Inject patches the pod template of a workload to consume a binding secret, either as environment
+// variables or as a mounted volume, and records the injection in the workload's bindings annotation
+func Inject(namespace string, w Workload, injection Injection) error {
+	_, err := util.UpdateResource(workloadPath(namespace, w), func(obj *unstructured.Unstructured) error {
+		err := updatePodSpec(obj, getWorkloadKind(w.Kind).templatePath, func(spec *v1.PodSpec) {
+			removeFromPodSpec(spec, injection.Secret)
+			injectIntoPodSpec(spec, injection)
+		})
+		if err != nil {
+			return err
+		}
+		injections, err := getInjections(obj)
+		if err != nil {
+			return err
+		}
+		return setInjections(obj, append(removeInjection(injections, injection.Secret), injection))
+	})
+	return err
+}
+
+// Uninject removes a binding secret injected by Inject from the pod template of a workload
+func Uninject(namespace string, w Workload, secretName string) error {
+	_, err := util.UpdateResource(workloadPath(namespace, w), func(obj *unstructured.Unstructured) error {
+		err := updatePodSpec(obj, getWorkloadKind(w.Kind).templatePath, func(spec *v1.PodSpec) {
+			removeFromPodSpec(spec, secretName)
+		})
+		if err != nil {
+			return err
+		}
+		injections, err := getInjections(obj)
+		if err != nil {
+			return err
+		}
+		return setInjections(obj, removeInjection(injections, secretName))
+	})
+	return err
+}
+
+// Convert the pod spec of the template at templatePath to a typed PodSpec, apply update and write it back
+func updatePodSpec(obj *unstructured.Unstructured, templatePath []string, update func(spec *v1.PodSpec)) error {
+	spec, err := getPodSpec(obj, templatePath)
+	if err != nil {
+		return err
+	}
+	update(spec)
+	templateMap, ok := unstructured.NestedMap(obj.Object, templatePath...)
+	if !ok {
+		return fmt.Errorf("%v [%v] has no pod template", obj.GetKind(), obj.GetName())
+	}
+	specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return err
+	}
+	templateMap["spec"] = specMap
+	unstructured.SetNestedField(obj.Object, templateMap, templatePath...)
+	return nil
+}
+
+// Add a binding secret to every container of a pod spec
+func injectIntoPodSpec(spec *v1.PodSpec, injection Injection) {
+	if injection.MountPath != "" {
+		volumeName := volumePrefix + injection.Secret
+		spec.Volumes = append(spec.Volumes, v1.Volume{
+			Name: volumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: injection.Secret,
+				},
+			},
+		})
+		for i := range spec.Containers {
+			spec.Containers[i].VolumeMounts = append(spec.Containers[i].VolumeMounts, v1.VolumeMount{
+				Name:      volumeName,
+				MountPath: injection.MountPath,
+				ReadOnly:  true,
+			})
+		}
+		return
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].EnvFrom = append(spec.Containers[i].EnvFrom, v1.EnvFromSource{
+			Prefix: injection.EnvPrefix,
+			SecretRef: &v1.SecretEnvSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: injection.Secret},
+			},
+		})
+	}
+}
+
+// Remove the envFrom sources and volumes apb added for a binding secret from a pod spec
+func removeFromPodSpec(spec *v1.PodSpec, secretName string) {
+	volumeName := volumePrefix + secretName
+	volumes := []v1.Volume{}
+	for _, v := range spec.Volumes {
+		if v.Name != volumeName {
+			volumes = append(volumes, v)
+		}
+	}
+	spec.Volumes = volumes
+
+	for i, c := range spec.Containers {
+		envFrom := []v1.EnvFromSource{}
+		for _, e := range c.EnvFrom {
+			if e.SecretRef == nil || e.SecretRef.Name != secretName {
+				envFrom = append(envFrom, e)
+			}
+		}
+		spec.Containers[i].EnvFrom = envFrom
+
+		mounts := []v1.VolumeMount{}
+		for _, m := range c.VolumeMounts {
+			if m.Name != volumeName {
+				mounts = append(mounts, m)
+			}
+		}
+		spec.Containers[i].VolumeMounts = mounts
+	}
+}
+
+// Read the injections recorded in the bindings annotation of a workload
+func getInjections(obj *unstructured.Unstructured) ([]Injection, error) {
+	injections := []Injection{}
+	value, ok := obj.GetAnnotations()[BindingsAnnotation]
+	if !ok {
+		return injections, nil
+	}
+	err := json.Unmarshal([]byte(value), &injections)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v annotation on [%v]: %v", BindingsAnnotation, obj.GetName(), err)
+	}
+	return injections, nil
+}
+
+// Record injections in the bindings annotation of a workload, dropping the annotation when empty
+func setInjections(obj *unstructured.Unstructured, injections []Injection) error {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if len(injections) == 0 {
+		delete(annotations, BindingsAnnotation)
+	} else {
+		value, err := json.Marshal(injections)
+		if err != nil {
+			return err
+		}
+		annotations[BindingsAnnotation] = string(value)
+	}
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+func removeInjection(injections []Injection, secretName string) []Injection {
+	remaining := []Injection{}
+	for _, i := range injections {
+		if i.Secret != secretName {
+			remaining = append(remaining, i)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,182 @@
+package binding
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseWorkload(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name      string
+		ref       string
+		workload  Workload
+		shouldErr bool
+	}{
+		{
+			name:     "test bare name",
+			ref:      "myapp",
+			workload: Workload{Kind: "DeploymentConfig", Name: "myapp"},
+		},
+		{
+			name:     "test short kind",
+			ref:      "deploy/myapp",
+			workload: Workload{Kind: "Deployment", Name: "myapp"},
+		},
+		{
+			name:     "test full kind",
+			ref:      "StatefulSet/db",
+			workload: Workload{Kind: "StatefulSet", Name: "db"},
+		},
+		{
+			name:     "test cronjob",
+			ref:      "cj/backup",
+			workload: Workload{Kind: "CronJob", Name: "backup"},
+		},
+		{
+			name:      "test unsupported kind",
+			ref:       "pod/myapp",
+			shouldErr: true,
+		},
+		{
+			name:      "test missing name",
+			ref:       "dc/",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := ParseWorkload(tc.ref)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error parsing [%v]", tc.ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing [%v]: %v", tc.ref, err)
+			}
+			if w != tc.workload {
+				t.Fatalf("expected workload %v, got %v", tc.workload, w)
+			}
+		})
+	}
+}
+
+func TestInjectAndRemove(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name      string
+		injection Injection
+	}{
+		{
+			name:      "test env injection",
+			injection: Injection{Secret: "db-creds", EnvPrefix: "DB_"},
+		},
+		{
+			name:      "test volume injection",
+			injection: Injection{Secret: "db-creds", MountPath: "/etc/db"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &v1.PodSpec{
+				Containers: []v1.Container{{Name: "app"}, {Name: "sidecar"}},
+			}
+			injectIntoPodSpec(spec, tc.injection)
+			for _, c := range spec.Containers {
+				if tc.injection.MountPath != "" {
+					if len(c.VolumeMounts) != 1 || c.VolumeMounts[0].MountPath != tc.injection.MountPath {
+						t.Fatalf("expected container [%v] to mount [%v], got %v", c.Name, tc.injection.MountPath, c.VolumeMounts)
+					}
+				} else {
+					if len(c.EnvFrom) != 1 || c.EnvFrom[0].Prefix != tc.injection.EnvPrefix {
+						t.Fatalf("expected container [%v] to have envFrom with prefix [%v], got %v", c.Name, tc.injection.EnvPrefix, c.EnvFrom)
+					}
+				}
+			}
+			removeFromPodSpec(spec, tc.injection.Secret)
+			if len(spec.Volumes) != 0 {
+				t.Fatalf("expected volumes to be removed, got %v", spec.Volumes)
+			}
+			for _, c := range spec.Containers {
+				if len(c.EnvFrom) != 0 || len(c.VolumeMounts) != 0 {
+					t.Fatalf("expected container [%v] to be cleaned up, got %v", c.Name, c)
+				}
+			}
+		})
+	}
+}
+
+func TestInjectionsAnnotation(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	injections := []Injection{{Secret: "a-creds"}, {Secret: "b-creds", MountPath: "/etc/b"}}
+	err := setInjections(obj, injections)
+	if err != nil {
+		t.Fatalf("unexpected error setting injections: %v", err)
+	}
+	got, err := getInjections(obj)
+	if err != nil {
+		t.Fatalf("unexpected error getting injections: %v", err)
+	}
+	if len(got) != 2 || got[1] != injections[1] {
+		t.Fatalf("expected injections %v, got %v", injections, got)
+	}
+	err = setInjections(obj, removeInjection(removeInjection(got, "a-creds"), "b-creds"))
+	if err != nil {
+		t.Fatalf("unexpected error setting injections: %v", err)
+	}
+	if _, ok := obj.GetAnnotations()[BindingsAnnotation]; ok {
+		t.Fatalf("expected bindings annotation to be removed")
+	}
+	obj.SetAnnotations(map[string]string{BindingsAnnotation: "[{"})
+	_, err = getInjections(obj)
+	if err == nil {
+		t.Fatalf("expected an error for a malformed bindings annotation")
+	}
+}
+
+func TestSecretRefs(t *testing.T) {
+	spec := &v1.PodSpec{
+		Volumes: []v1.Volume{
+			{Name: "creds", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "vol-creds"}}},
+			{Name: "data", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		},
+		InitContainers: []v1.Container{
+			{
+				Name: "init",
+				EnvFrom: []v1.EnvFromSource{
+					{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "env-creds"}}},
+				},
+			},
+		},
+		Containers: []v1.Container{
+			{
+				Name: "app",
+				EnvFrom: []v1.EnvFromSource{
+					{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "env-creds"}}},
+					{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}},
+				},
+				Env: []v1.EnvVar{
+					{Name: "PLAIN", Value: "value"},
+					{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "key-creds"},
+						Key:                  "password",
+					}}},
+				},
+			},
+		},
+	}
+	expected := []string{"vol-creds", "env-creds", "key-creds"}
+	refs := secretRefs(spec)
+	if len(refs) != len(expected) {
+		t.Fatalf("expected secret refs %v, got %v", expected, refs)
+	}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Fatalf("expected secret refs %v, got %v", expected, refs)
+		}
+	}
+}
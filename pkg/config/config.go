@@ -111,6 +111,7 @@ func InitialDefaultSettings() *DefaultSettings {
 		BrokerRouteName:          "broker",
 		ClusterServiceBrokerName: "openshift-automation-service-broker",
 		BrokerRouteSuffix:        "osb",
+		BrokerScope:              "auto",
 	}
 }
 
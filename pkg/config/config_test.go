@@ -1,9 +1,12 @@
 package config
 
 import (
-	"github.com/automationbroker/bundle-lib/registries"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/automationbroker/bundle-lib/registries"
 )
 
 const defaultConfigDir = "testdata/.apb"
@@ -158,10 +161,12 @@ func TestInitializeDefaultSettings(t *testing.T) {
 	testCases := []struct {
 		name      string
 		namespace string
+		scope     string
 	}{
 		{
 			name:      "test default namespace",
 			namespace: "openshift-automation-service-broker",
+			scope:     "auto",
 		},
 	}
 	for _, tc := range testCases {
@@ -170,6 +175,50 @@ func TestInitializeDefaultSettings(t *testing.T) {
 			if settings.BrokerNamespace != tc.namespace {
 				t.Fatalf("Expected default namespace [%v], got [%v]", tc.namespace, settings.BrokerNamespace)
 			}
+			if settings.BrokerScope != tc.scope {
+				t.Fatalf("Expected default broker scope [%v], got [%v]", tc.scope, settings.BrokerScope)
+			}
 		})
 	}
 }
+
+func TestLoadPodSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defaults := `{
+  "defaults": {
+    "Pod": {
+      "ImagePullPolicy": "IfNotPresent",
+      "CPULimit": "500m",
+      "NodeSelector": ["node-role.kubernetes.io/apb=true"],
+      "Tolerations": ["dedicated=apb:NoSchedule"],
+      "RunAsUser": 1001,
+      "Labels": ["costCenter=1234"]
+    }
+  }
+}`
+	err = ioutil.WriteFile(filepath.Join(dir, "defaults.json"), []byte(defaults), 0644)
+	if err != nil {
+		t.Fatalf("unable to write defaults: %v", err)
+	}
+	viperConfig, _ := InitJSONConfig(dir, "defaults")
+	settings := DefaultSettings{}
+	LoadDefaultSettings(viperConfig, &settings)
+
+	pod := settings.Pod
+	if pod.ImagePullPolicy != "IfNotPresent" || pod.CPULimit != "500m" {
+		t.Fatalf("unexpected pod settings %+v", pod)
+	}
+	if len(pod.NodeSelector) != 1 || pod.NodeSelector[0] != "node-role.kubernetes.io/apb=true" {
+		t.Fatalf("unexpected node selector %v", pod.NodeSelector)
+	}
+	if len(pod.Tolerations) != 1 || len(pod.Labels) != 1 || pod.Labels[0] != "costCenter=1234" {
+		t.Fatalf("unexpected tolerations %v or labels %v", pod.Tolerations, pod.Labels)
+	}
+	if pod.RunAsUser == nil || *pod.RunAsUser != 1001 || pod.FSGroup != nil {
+		t.Fatalf("unexpected security context settings %+v", pod)
+	}
+}
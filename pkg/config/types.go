@@ -9,6 +9,10 @@ import (
 type ProvisionedInstance struct {
 	BundleName  string
 	InstanceIDs map[string][]string
+	// TargetNamespaces holds the extra target namespaces of instances, by instance ID
+	TargetNamespaces map[string][]string
+	// LastOperations holds the final description reported by the provision of instances, by instance ID
+	LastOperations map[string]string
 }
 
 // Registry stores a single registry config and references all associated bundle specs
@@ -24,4 +28,32 @@ type DefaultSettings struct {
 	BrokerRouteName          string
 	ClusterServiceBrokerName string
 	BrokerRouteSuffix        string
+	BrokerScope              string
+	Pod                      PodSettings
+	Proxy                    ProxySettings
+	StateNamespace           string
+}
+
+// ProxySettings are passed to APB pods as the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+type ProxySettings struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// PodSettings customizes the pods running APB actions. Node selectors, labels and annotations are
+// key=value strings, tolerations are key[=value]:effect strings like taints.
+type PodSettings struct {
+	ImagePullPolicy string
+	CPURequest      string
+	CPULimit        string
+	MemoryRequest   string
+	MemoryLimit     string
+	NodeSelector    []string
+	Tolerations     []string
+	RunAsUser       *int64
+	RunAsNonRoot    bool
+	FSGroup         *int64
+	Labels          []string
+	Annotations     []string
 }
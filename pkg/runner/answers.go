@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/ghodss/yaml"
+)
+
+// secretPlaceholder replaces sensitive values entered at the prompt in saved answers
+const secretPlaceholder = secretRefPrefix + "NAMESPACE/SECRET/KEY"
+
+// Answers records the plan and parameters chosen for an APB, so a run can be repeated
+type Answers struct {
+	Bundle     string                 `json:"bundle"`
+	Plan       string                 `json:"plan"`
+	Parameters map[string]interface{} `json:"parameters"`
+	// PlanParameters lists the plan's parameters when the answers were saved, to spot changes in newer APB versions
+	PlanParameters []string `json:"planParameters"`
+}
+
+// loadAnswers reads an answers file written by saveAnswers
+func loadAnswers(path string) (*Answers, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read answers file: %v", err)
+	}
+	answers := &Answers{}
+	err = yaml.Unmarshal(data, answers)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse answers file [%v]: %v", path, err)
+	}
+	return answers, nil
+}
+
+// saveAnswers writes the plan and parameters of a run. Sensitive values are written as the reference
+// they were given with, or as a placeholder to be replaced with a secret reference.
+func saveAnswers(path string, bundleName string, plan bundle.Plan, params bundle.Parameters, sensitive map[string]bool, provided map[string]string) error {
+	answers := Answers{
+		Bundle:         bundleName,
+		Plan:           plan.Name,
+		Parameters:     map[string]interface{}{},
+		PlanParameters: planParameterNames(plan),
+	}
+	for name, value := range params {
+		if sensitive[name] {
+			if isParamRef(provided[name]) {
+				value = provided[name]
+			} else {
+				value = secretPlaceholder
+			}
+		}
+		answers.Parameters[name] = value
+	}
+	data, err := yaml.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// answerInputs converts answers to parameter inputs, as if given with --param, for the parameters the plan still has
+func answerInputs(answers *Answers, plan bundle.Plan) (map[string]string, error) {
+	inputs := map[string]string{}
+	for name, value := range answers.Parameters {
+		if !hasParameter(plan, name) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if v == secretPlaceholder {
+				return nil, fmt.Errorf("parameter [%v] in the answers file still has the placeholder [%v]. Replace it with a reference to the secret holding the value", name, secretPlaceholder)
+			}
+			inputs[name] = v
+		default:
+			input, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			inputs[name] = string(input)
+		}
+	}
+	return inputs, nil
+}
+
+// compareAnswerParameters returns the parameters added to and removed from the plan since the answers were saved
+func compareAnswerParameters(answers *Answers, plan bundle.Plan) (added []string, removed []string) {
+	for _, name := range planParameterNames(plan) {
+		if !contains(answers.PlanParameters, name) {
+			added = append(added, name)
+		}
+	}
+	for _, name := range answers.PlanParameters {
+		if !hasParameter(plan, name) {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
+
+func planParameterNames(plan bundle.Plan) []string {
+	names := []string{}
+	for _, param := range plan.Parameters {
+		names = append(names, param.Name)
+	}
+	sort.Strings(names)
+	return names
+}
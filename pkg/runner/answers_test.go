@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestSaveAndLoadAnswers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-answers")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "answers.yml")
+
+	plan := bundle.Plan{
+		Name: "dev",
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "user", Type: "string"},
+			{Name: "password", Type: "string", DisplayType: "password"},
+			{Name: "token", Type: "string"},
+			{Name: "replicas", Type: "integer"},
+			{Name: "labels", Type: "object"},
+		},
+	}
+	params := bundle.Parameters{
+		"user":     "admin",
+		"password": "hunter2",
+		"token":    "s3cr3t",
+		"replicas": int64(2),
+		"labels":   map[string]interface{}{"app": "db"},
+	}
+	sensitive := map[string]bool{"password": true, "token": true}
+	provided := map[string]string{"token": "@env:DB_TOKEN"}
+
+	err = saveAnswers(path, "postgresql-apb", plan, params, sensitive, provided)
+	if err != nil {
+		t.Fatalf("unexpected error saving answers: %v", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "s3cr3t") {
+		t.Fatalf("expected sensitive values to be left out of answers file, got:\n%s", data)
+	}
+
+	answers, err := loadAnswers(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading answers: %v", err)
+	}
+	if answers.Bundle != "postgresql-apb" || answers.Plan != "dev" {
+		t.Fatalf("unexpected bundle and plan [%v] [%v]", answers.Bundle, answers.Plan)
+	}
+
+	_, err = answerInputs(answers, plan)
+	if err == nil {
+		t.Fatalf("expected error replaying answers with a secret placeholder")
+	}
+	answers.Parameters["password"] = "@secret:db/pg/password"
+	inputs, err := answerInputs(answers, plan)
+	if err != nil {
+		t.Fatalf("unexpected error replaying answers: %v", err)
+	}
+	expected := map[string]string{
+		"user":     "admin",
+		"password": "@secret:db/pg/password",
+		"token":    "@env:DB_TOKEN",
+		"replicas": "2",
+		"labels":   `{"app":"db"}`,
+	}
+	for name, value := range expected {
+		if inputs[name] != value {
+			t.Fatalf("expected input [%v] to be [%v], got [%v]", name, value, inputs[name])
+		}
+	}
+}
+
+func TestCompareAnswerParameters(t *testing.T) {
+	answers := &Answers{PlanParameters: []string{"password", "user", "version"}}
+	plan := bundle.Plan{
+		Parameters: []bundle.ParameterDescriptor{
+			{Name: "user"},
+			{Name: "password"},
+			{Name: "storage"},
+		},
+	}
+	added, removed := compareAnswerParameters(answers, plan)
+	if len(added) != 1 || added[0] != "storage" {
+		t.Fatalf("expected [storage] to be added, got %v", added)
+	}
+	if len(removed) != 1 || removed[0] != "version" {
+		t.Fatalf("expected [version] to be removed, got %v", removed)
+	}
+}
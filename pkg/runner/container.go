@@ -0,0 +1,367 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// apb-base logs in to the cluster with these variables when it doesn't run in a pod
+const (
+	openshiftTargetEnv = "OPENSHIFT_TARGET"
+	openshiftTokenEnv  = "OPENSHIFT_TOKEN"
+)
+
+// How long to wait for the token of the sandbox service account
+const (
+	tokenRetries    = 30
+	tokenRetryDelay = time.Second
+)
+
+// containerPullPolicies maps the image pull policy of the pod to the --pull option of docker and podman
+var containerPullPolicies = map[v1.PullPolicy]string{
+	v1.PullAlways:       "always",
+	v1.PullIfNotPresent: "missing",
+	v1.PullNever:        "never",
+}
+
+// containerExecutor runs APBs as local containers with docker or podman. The container logs in to
+// the cluster with the token of the sandbox service account, so the APB has the same permissions
+// as in a pod. Files mounted in the pod, such as the extra-vars, are copied into the container
+// before it starts.
+type containerExecutor struct {
+	command string
+}
+
+func (e containerExecutor) Start(ec runtime.ExecutionContext, pod *v1.Pod, inlineExtraVars bool) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	token, err := serviceAccountToken(ec.Location, ec.Account)
+	if err != nil {
+		return err
+	}
+	container := pod.Spec.Containers[0]
+	sources, err := envFromData(ec.Location, container.EnvFrom)
+	if err != nil {
+		return err
+	}
+	env := containerEnv(container, ec, sources)
+	env = append(env, v1.EnvVar{Name: openshiftTargetEnv, Value: k8scli.ClientConfig.Host})
+	env = append(env, v1.EnvVar{Name: openshiftTokenEnv, Value: token})
+	files, err := containerFiles(ec, pod)
+	if err != nil {
+		return err
+	}
+
+	ignored := ignoredPodSettings(pod)
+	if len(ignored) > 0 {
+		log.Warningf("Ignoring pod settings not supported by %v: %v", e.command, strings.Join(ignored, ", "))
+	}
+	// Values are passed through the environment of the command, to keep them off its arguments
+	names := []string{}
+	values := os.Environ()
+	for _, v := range env {
+		names = append(names, v.Name)
+		values = append(values, fmt.Sprintf("%v=%v", v.Name, v.Value))
+	}
+	_, err = e.run(values, containerCreateArgs(pod, names)...)
+	if err != nil {
+		return err
+	}
+	err = e.start(pod.Name, files)
+	if err != nil {
+		e.remove(pod.Name)
+		return err
+	}
+	fmt.Printf("Successfully started container [%v] with %v to %s [%v]\n", pod.Name, e.command, ec.Action, ec.Metadata[BundleFQNameLabel])
+	return nil
+}
+
+func (e containerExecutor) PrintLogs(name string, namespace string, opts LogOptions) error {
+	out, closeOut, err := logOutput(opts.LogFile)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+	if opts.Follow {
+		fmt.Printf("Container started. Reading logs...\n")
+	}
+	printLogsHeader()
+
+	args := []string{"logs"}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	cmd := exec.Command(e.command, append(args, name)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// Wait waits for the container to finish and removes it, since it holds the token and the extra-vars
+func (e containerExecutor) Wait(name string, namespace string, showProgress bool) (Result, error) {
+	if showProgress {
+		fmt.Printf("Waiting for container [%v] to finish...\n", name)
+	}
+	defer e.remove(name)
+	out, err := e.run(nil, "wait", name)
+	if err != nil {
+		return Result{}, err
+	}
+	code := strings.TrimSpace(out)
+	if code != "0" {
+		return Result{}, fmt.Errorf("container [%v] exited with code %v", name, code)
+	}
+	return Result{}, nil
+}
+
+// start copies the files mounted in the pod into a created container and starts it
+func (e containerExecutor) start(name string, files map[string]map[string]string) error {
+	for mountPath, data := range files {
+		err := e.copyFiles(name, mountPath, data)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := e.run(nil, "start", name)
+	return err
+}
+
+// remove removes a container, whether it is running or not
+func (e containerExecutor) remove(name string) {
+	_, err := e.run(nil, "rm", "--force", name)
+	if err != nil {
+		log.Warningf("Unable to remove container [%v]: %v", name, err)
+	}
+}
+
+// run runs docker or podman, with env as its environment if it isn't nil
+func (e containerExecutor) run(env []string, args ...string) (string, error) {
+	cmd := exec.Command(e.command, args...)
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v %v failed: %v: %s", e.command, args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// copyFiles copies files into a created container, creating the directory holding them
+func (e containerExecutor) copyFiles(name string, mountPath string, data map[string]string) error {
+	tmp, err := ioutil.TempDir("", "apb-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	// The APB doesn't run as root, the copies must be readable by anyone. The temporary
+	// directory itself is only readable by the user running apb.
+	dir := filepath.Join(tmp, "files")
+	err = os.Mkdir(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for key, value := range data {
+		err = ioutil.WriteFile(filepath.Join(dir, key), []byte(value), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = e.run(nil, "cp", dir+"/.", fmt.Sprintf("%v:%v", name, mountPath))
+	return err
+}
+
+// serviceAccountToken returns the token of a service account, waiting for it to be created
+func serviceAccountToken(namespace string, name string) (string, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < tokenRetries; i++ {
+		sa, err := k8scli.Client.CoreV1().ServiceAccounts(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, ref := range sa.Secrets {
+			secret, err := k8scli.Client.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			if secret.Type == v1.SecretTypeServiceAccountToken && len(secret.Data[v1.ServiceAccountTokenKey]) > 0 {
+				return string(secret.Data[v1.ServiceAccountTokenKey]), nil
+			}
+		}
+		time.Sleep(tokenRetryDelay)
+	}
+	return "", fmt.Errorf("no token was created for service account [%v] in namespace [%v]", name, namespace)
+}
+
+// envFromData reads the variables set from secrets and configmaps, in order
+func envFromData(namespace string, sources []v1.EnvFromSource) ([]v1.EnvVar, error) {
+	env := []v1.EnvVar{}
+	if len(sources) == 0 {
+		return env, nil
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		data := map[string]string{}
+		if source.ConfigMapRef != nil {
+			cm, err := k8scli.Client.CoreV1().ConfigMaps(namespace).Get(source.ConfigMapRef.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			data = cm.Data
+		}
+		if source.SecretRef != nil {
+			secret, err := k8scli.Client.CoreV1().Secrets(namespace).Get(source.SecretRef.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range secret.Data {
+				data[key] = string(value)
+			}
+		}
+		keys := []string{}
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			env = append(env, v1.EnvVar{Name: key, Value: data[key]})
+		}
+	}
+	return env, nil
+}
+
+// containerEnv resolves the environment of the APB container. Variables of the container replace
+// the ones set from secrets and configmaps, as in a pod.
+func containerEnv(container v1.Container, ec runtime.ExecutionContext, sources []v1.EnvVar) []v1.EnvVar {
+	env := []v1.EnvVar{}
+	for _, e := range container.Env {
+		if e.ValueFrom != nil && e.ValueFrom.FieldRef != nil {
+			switch e.ValueFrom.FieldRef.FieldPath {
+			case "metadata.name":
+				e = v1.EnvVar{Name: e.Name, Value: ec.BundleName}
+			case "metadata.namespace":
+				e = v1.EnvVar{Name: e.Name, Value: ec.Location}
+			}
+		}
+		env = append(env, e)
+	}
+	return mergeEnv(sources, env)
+}
+
+// containerFiles returns the files of the volumes mounted in the APB pod by mount path: the
+// extra-vars and the state of the instance
+func containerFiles(ec runtime.ExecutionContext, pod *v1.Pod) (map[string]map[string]string, error) {
+	files := map[string]map[string]string{}
+	for _, mount := range pod.Spec.Containers[0].VolumeMounts {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Name != mount.Name {
+				continue
+			}
+			switch {
+			case volume.Secret != nil && volume.Secret.SecretName == extraVarsSecretName(ec.BundleName):
+				files[mount.MountPath] = map[string]string{extraVarsKey: ec.ExtraVars}
+			case volume.ConfigMap != nil:
+				k8scli, err := clients.Kubernetes()
+				if err != nil {
+					return nil, err
+				}
+				cm, err := k8scli.Client.CoreV1().ConfigMaps(ec.Location).Get(volume.ConfigMap.Name, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				files[mount.MountPath] = cm.Data
+			}
+		}
+	}
+	return files, nil
+}
+
+// containerCreateArgs returns the arguments creating the APB container from its pod, passing the
+// environment variables with the given names. The container shares the network of the host, to
+// reach clusters listening on localhost.
+func containerCreateArgs(pod *v1.Pod, envNames []string) []string {
+	container := pod.Spec.Containers[0]
+	args := []string{"create", "--name", pod.Name, "--network", "host"}
+	labels := []string{}
+	for key, value := range pod.Labels {
+		labels = append(labels, fmt.Sprintf("%v=%v", key, value))
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		args = append(args, "--label", label)
+	}
+	if policy, ok := containerPullPolicies[container.ImagePullPolicy]; ok {
+		args = append(args, "--pull", policy)
+	}
+	if sc := pod.Spec.SecurityContext; sc != nil && sc.RunAsUser != nil {
+		args = append(args, "--user", strconv.FormatInt(*sc.RunAsUser, 10))
+	}
+	if cpu, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(cpu.MilliValue())/1000, 'f', -1, 64))
+	}
+	if memory, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+		args = append(args, "--memory", strconv.FormatInt(memory.Value(), 10))
+	}
+	for _, name := range envNames {
+		args = append(args, "--env", name)
+	}
+	args = append(args, container.Image)
+	return append(args, container.Args...)
+}
+
+// ignoredPodSettings lists the pod settings local containers don't support
+func ignoredPodSettings(pod *v1.Pod) []string {
+	ignored := []string{}
+	if len(pod.Spec.NodeSelector) > 0 {
+		ignored = append(ignored, "node selector")
+	}
+	if len(pod.Spec.Tolerations) > 0 {
+		ignored = append(ignored, "tolerations")
+	}
+	if len(pod.Spec.Containers[0].Resources.Requests) > 0 {
+		ignored = append(ignored, "resource requests")
+	}
+	if sc := pod.Spec.SecurityContext; sc != nil && (sc.RunAsNonRoot != nil || sc.FSGroup != nil) {
+		ignored = append(ignored, "security context")
+	}
+	return ignored
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+)
+
+func TestContainerCreateArgs(t *testing.T) {
+	runAsUser := int64(1001)
+	settings := config.PodSettings{
+		ImagePullPolicy: "IfNotPresent",
+		CPULimit:        "500m",
+		MemoryLimit:     "512Mi",
+		RunAsUser:       &runAsUser,
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Image: "docker.io/ansibleplaybookbundle/postgresql-apb",
+		Args:  []string{"provision", "--extra-vars", "@/etc/apb-extra-vars/extra-vars.json"},
+	}}}}
+	pod.Name = "bundle-provision-1234"
+	pod.Labels = map[string]string{BundleActionLabel: "provision"}
+	err := applyPodSettings(pod, settings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := containerCreateArgs(pod, []string{"POD_NAME", "OPENSHIFT_TOKEN"})
+	expected := "create --name bundle-provision-1234 --network host --label bundle-action=provision " +
+		"--pull missing --user 1001 --cpus 0.5 --memory 536870912 --env POD_NAME --env OPENSHIFT_TOKEN " +
+		"docker.io/ansibleplaybookbundle/postgresql-apb provision --extra-vars @/etc/apb-extra-vars/extra-vars.json"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("expected [%v], got [%v]", expected, strings.Join(args, " "))
+	}
+}
+
+func TestContainerEnv(t *testing.T) {
+	ec := runtime.ExecutionContext{BundleName: "bundle-provision-1234", Location: "myproject"}
+	container := v1.Container{Env: createPodEnv(ec, []v1.EnvVar{{Name: "DEBUG", Value: "true"}})}
+	sources := []v1.EnvVar{{Name: "DEBUG", Value: "false"}, {Name: "REGION", Value: "eu"}}
+
+	expected := map[string]string{
+		"POD_NAME":      "bundle-provision-1234",
+		"POD_NAMESPACE": "myproject",
+		"DEBUG":         "true",
+		"REGION":        "eu",
+	}
+	env := containerEnv(container, ec, sources)
+	if len(env) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, env)
+	}
+	for _, e := range env {
+		if v, ok := expected[e.Name]; !ok || v != e.Value || e.ValueFrom != nil {
+			t.Fatalf("unexpected variable %v=%v", e.Name, e.Value)
+		}
+	}
+}
+
+func TestContainerFiles(t *testing.T) {
+	ec := runtime.ExecutionContext{BundleName: "bundle-provision-1234", ExtraVars: `{"namespace":"myproject"}`}
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{}}}}
+	addExtraVarsVolume(pod, extraVarsSecretName(ec.BundleName))
+
+	files, err := containerFiles(ec, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[extraVarsMountPath][extraVarsKey] != ec.ExtraVars {
+		t.Fatalf("expected extra-vars at [%v], got %v", extraVarsMountPath, files)
+	}
+}
+
+func TestIgnoredPodSettings(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{}}}}
+	err := applyPodSettings(pod, config.PodSettings{
+		NodeSelector: []string{"zone=east"},
+		CPURequest:   "100m",
+		CPULimit:     "500m",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ignored := ignoredPodSettings(pod)
+	if strings.Join(ignored, ", ") != "node selector, resource requests" {
+		t.Fatalf("unexpected ignored settings %v", ignored)
+	}
+}
+
+func TestContainerWaitRemoves(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apb-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// Fake docker recording its arguments, with the container exiting with code 1
+	command := filepath.Join(dir, "docker")
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\n[ \"$1\" = wait ] && echo 1\nexit 0\n"
+	err = ioutil.WriteFile(command, []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := containerExecutor{command: command}
+	_, err = e.Wait("bundle-test-1234", "myproject", false)
+	if err == nil || !strings.Contains(err.Error(), "exited with code 1") {
+		t.Fatalf("expected the container to fail, got %v", err)
+	}
+	out, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "wait bundle-test-1234\nrm --force bundle-test-1234\n"
+	if string(out) != expected {
+		t.Fatalf("expected calls %q, got %q", expected, string(out))
+	}
+}
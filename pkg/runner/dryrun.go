@@ -0,0 +1,230 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"github.com/ghodss/yaml"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	rbac "k8s.io/api/rbac/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Dry-run modes. Client dry-run only prints the objects, server dry-run also submits them to the
+// API server with dryRun, which validates them and runs admission without persisting them.
+const (
+	DryRunClient = "client"
+	DryRunServer = "server"
+)
+
+// dryRunObject is an object RunBundle would create, along with its resource
+type dryRunObject struct {
+	resource  string
+	namespace string
+	object    interface{}
+}
+
+// validateDryRun checks the dry-run mode
+func validateDryRun(mode string, sandboxNamespace string) error {
+	switch mode {
+	case "", DryRunClient:
+		return nil
+	case DryRunServer:
+		// The objects of the transient namespace can't be checked before it exists
+		if sandboxNamespace != "" {
+			return fmt.Errorf("server dry-run can't be combined with a sandbox namespace")
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid dry-run mode [%v], expected %v or %v", mode, DryRunClient, DryRunServer)
+}
+
+// dryRunObjects returns the objects created to run an APB pod: the transient namespace and its network
+// policy, the sandbox service account and role bindings, the extra-vars secret and the pod itself.
+// In a transient namespace, ec.Location is the prefix of its generated name.
+func dryRunObjects(ec runtime.ExecutionContext, ns string, sandboxRole string, pod *v1.Pod, inlineExtraVars bool) []dryRunObject {
+	objects := []dryRunObject{}
+	if ec.Location != ns {
+		objects = append(objects, dryRunObject{
+			resource: "namespaces",
+			object: &v1.Namespace{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+				ObjectMeta: metav1.ObjectMeta{GenerateName: ec.Location, Labels: ec.Metadata},
+			},
+		}, dryRunObject{
+			resource:  "networkpolicies",
+			namespace: ec.Targets[0],
+			object: &networkingv1.NetworkPolicy{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+				ObjectMeta: metav1.ObjectMeta{Name: ec.BundleName, Namespace: ec.Targets[0]},
+				Spec: networkingv1.NetworkPolicySpec{
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From: []networkingv1.NetworkPolicyPeer{{
+							NamespaceSelector: metav1.AddLabelToSelector(&metav1.LabelSelector{}, "apb-pod-name", ec.BundleName),
+						}},
+					}},
+				},
+			},
+		})
+	}
+	objects = append(objects, dryRunObject{
+		resource:  "serviceaccounts",
+		namespace: ec.Location,
+		object: &v1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: metav1.ObjectMeta{Name: ec.Account, Namespace: ec.Location},
+		},
+	})
+	bindingNamespaces := []string{ec.Location}
+	for _, target := range ec.Targets {
+		if target != ec.Location {
+			bindingNamespaces = append(bindingNamespaces, target)
+		}
+	}
+	for _, target := range bindingNamespaces {
+		objects = append(objects, dryRunObject{
+			resource:  "rolebindings",
+			namespace: target,
+			object: &rbac.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1beta1", Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: ec.BundleName, Namespace: target},
+				Subjects: []rbac.Subject{{
+					Kind:      "ServiceAccount",
+					Name:      ec.Account,
+					Namespace: ec.Location,
+				}},
+				RoleRef: rbac.RoleRef{
+					APIGroup: "rbac.authorization.k8s.io",
+					Kind:     "ClusterRole",
+					Name:     sandboxRole,
+				},
+			},
+		})
+	}
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	pod.Namespace = ec.Location
+	objects = append(objects, dryRunObject{resource: "pods", namespace: ec.Location, object: pod})
+	if !inlineExtraVars {
+		secret := extraVarsSecret(pod, extraVarsSecretName(ec.BundleName), nil)
+		secret.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+		secret.Namespace = ec.Location
+		// Shown as text, the extra-vars are redacted
+		secret.Data = nil
+		secret.StringData = map[string]string{extraVarsKey: ec.ExtraVars}
+		objects = append(objects, dryRunObject{resource: "secrets", namespace: ec.Location, object: secret})
+	}
+	return objects
+}
+
+// dryRunBundle prints the objects that would be created to run an APB action
+func dryRunBundle(ec runtime.ExecutionContext, ns string, id string, sandboxRole string, customEnv []v1.EnvVar, opts RunOptions) error {
+	if ec.Action != "provision" {
+		present, err := configMapExists(runtime.Provider.MasterNamespace(), runtime.Provider.MasterName(id))
+		if err != nil {
+			return err
+		}
+		if present {
+			ec.StateName = ec.BundleName
+			ec.StateLocation = runtime.Provider.MountLocation()
+		}
+	}
+	pod, err := newBundlePod(ec, customEnv, opts)
+	if err != nil {
+		return err
+	}
+	return printDryRun(opts.DryRun, dryRunObjects(ec, ns, sandboxRole, pod, opts.InlineExtraVars))
+}
+
+// printDryRun prints the objects an APB action would create as YAML, after submitting them to the
+// API server with a server dry-run
+func printDryRun(mode string, objects []dryRunObject) error {
+	var podUID types.UID
+	for _, o := range objects {
+		if mode != DryRunServer {
+			out, err := yaml.Marshal(o.object)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("---\n%s", out)
+			continue
+		}
+		switch object := o.object.(type) {
+		case *v1.Pod:
+			// The sandbox service account isn't created by a dry-run, and admission rejects pods
+			// whose service account doesn't exist
+			fmt.Printf("# Pod checked with the default service account instead of [%v]\n", object.Spec.ServiceAccountName)
+			pod := object.DeepCopy()
+			pod.Spec.ServiceAccountName = ""
+			o.object = pod
+		case *v1.Secret:
+			// The owner reference needs the UID the API server gave the pod
+			for i := range object.OwnerReferences {
+				object.OwnerReferences[i].UID = podUID
+			}
+		}
+		created, err := submitDryRun(o)
+		if err != nil {
+			return err
+		}
+		if o.resource == "pods" {
+			podUID = created.GetUID()
+		}
+		out, err := yaml.Marshal(created.Object)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", out)
+	}
+	return nil
+}
+
+// submitDryRun creates an object with dryRun and returns the object the API server would have stored
+func submitDryRun(o dryRunObject) (*unstructured.Unstructured, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	var client rest.Interface
+	switch o.resource {
+	case "networkpolicies":
+		client = k8scli.Client.NetworkingV1().RESTClient()
+	case "rolebindings":
+		client = k8scli.Client.RbacV1beta1().RESTClient()
+	default:
+		client = k8scli.Client.CoreV1().RESTClient()
+	}
+	body, err := json.Marshal(o.object)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.Post().Namespace(o.namespace).Resource(o.resource).Param("dryRun", "All").Body(body).Do().Raw()
+	if err != nil {
+		return nil, fmt.Errorf("server dry-run of %v failed: %v", o.resource, err)
+	}
+	created := &unstructured.Unstructured{}
+	err = json.Unmarshal(raw, &created.Object)
+	return created, err
+}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+
+	rbac "k8s.io/api/rbac/v1beta1"
+)
+
+func TestValidateDryRun(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name             string
+		mode             string
+		sandboxNamespace string
+		shouldErr        bool
+	}{
+		{
+			name: "no dry-run",
+			mode: "",
+		},
+		{
+			name:             "client dry-run in a transient namespace",
+			mode:             DryRunClient,
+			sandboxNamespace: AutoSandboxNamespace,
+		},
+		{
+			name: "server dry-run",
+			mode: DryRunServer,
+		},
+		{
+			name:             "server dry-run in a transient namespace",
+			mode:             DryRunServer,
+			sandboxNamespace: AutoSandboxNamespace,
+			shouldErr:        true,
+		},
+		{
+			name:      "invalid mode",
+			mode:      "all",
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateDryRun(tc.mode, tc.sandboxNamespace)
+			if tc.shouldErr && err == nil {
+				t.Fatalf("expected an error for mode [%v]", tc.mode)
+			}
+			if !tc.shouldErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDryRunObjects(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name              string
+		location          string
+		inlineExtraVars   bool
+		expectedResources []string
+	}{
+		{
+			name:              "sandbox in the namespace",
+			location:          "myproject",
+			expectedResources: []string{"serviceaccounts", "rolebindings", "rolebindings", "pods", "secrets"},
+		},
+		{
+			name:              "inline extra-vars",
+			location:          "myproject",
+			inlineExtraVars:   true,
+			expectedResources: []string{"serviceaccounts", "rolebindings", "rolebindings", "pods"},
+		},
+		{
+			name:              "transient namespace",
+			location:          "postgresql-apb-prov-",
+			expectedResources: []string{"namespaces", "networkpolicies", "serviceaccounts", "rolebindings", "rolebindings", "rolebindings", "pods", "secrets"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := runtime.ExecutionContext{
+				BundleName: "bundle-provision-1234",
+				Account:    "bundle-provision-1234",
+				Targets:    []string{"myproject", "shared"},
+				Location:   tc.location,
+				ExtraVars:  `{"password":"<redacted>"}`,
+			}
+			pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{}}}}
+			objects := dryRunObjects(ec, "myproject", "edit", pod, tc.inlineExtraVars)
+			if len(objects) != len(tc.expectedResources) {
+				t.Fatalf("expected %v, got %v objects", tc.expectedResources, len(objects))
+			}
+			for i, o := range objects {
+				if o.resource != tc.expectedResources[i] {
+					t.Fatalf("expected %v, got [%v] at %d", tc.expectedResources, o.resource, i)
+				}
+				if binding, ok := o.object.(*rbac.RoleBinding); ok {
+					if binding.Subjects[0].Namespace != tc.location || binding.RoleRef.Name != "edit" {
+						t.Fatalf("unexpected role binding %v", binding)
+					}
+				}
+				if secret, ok := o.object.(*v1.Secret); ok && secret.StringData[extraVarsKey] != ec.ExtraVars {
+					t.Fatalf("expected extra-vars [%v], got %v", ec.ExtraVars, secret.StringData)
+				}
+			}
+			if pod.Namespace != tc.location {
+				t.Fatalf("expected pod in [%v], got [%v]", tc.location, pod.Namespace)
+			}
+		})
+	}
+}
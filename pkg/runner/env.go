@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	httpProxyEnvVar  = "HTTP_PROXY"
+	httpsProxyEnvVar = "HTTPS_PROXY"
+	noProxyEnvVar    = "NO_PROXY"
+	// stateLocationEnvVar tells apb-base where the state of the instance is mounted
+	stateLocationEnvVar = "BUNDLE_STATE_LOCATION"
+)
+
+// reservedEnv are set by apb on APB pods
+var reservedEnv = []string{"POD_NAME", "POD_NAMESPACE", stateLocationEnvVar}
+
+// proxyConfig returns nil when no proxy is configured
+func proxyConfig(settings config.ProxySettings) *runtime.ProxyConfig {
+	if settings.HTTPProxy == "" && settings.HTTPSProxy == "" && settings.NoProxy == "" {
+		return nil
+	}
+	return &runtime.ProxyConfig{
+		HTTPProxy:  settings.HTTPProxy,
+		HTTPSProxy: settings.HTTPSProxy,
+		NoProxy:    settings.NoProxy,
+	}
+}
+
+// proxyEnv sets the proxy variables in upper and lower case, the same way the broker does
+func proxyEnv(conf *runtime.ProxyConfig) []v1.EnvVar {
+	if conf == nil {
+		return nil
+	}
+	env := []v1.EnvVar{}
+	for _, v := range []v1.EnvVar{
+		{Name: httpProxyEnvVar, Value: conf.HTTPProxy},
+		{Name: httpsProxyEnvVar, Value: conf.HTTPSProxy},
+		{Name: noProxyEnvVar, Value: conf.NoProxy},
+	} {
+		if v.Value == "" {
+			continue
+		}
+		env = append(env, v, v1.EnvVar{Name: strings.ToLower(v.Name), Value: v.Value})
+	}
+	return env
+}
+
+// parseEnvArgs parses KEY=VALUE pairs, keeping their order
+func parseEnvArgs(args []string) ([]v1.EnvVar, error) {
+	env := []v1.EnvVar{}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("environment variable [%v] must be given as KEY=VALUE", arg)
+		}
+		if contains(reservedEnv, kv[0]) {
+			return nil, fmt.Errorf("environment variable [%v] is set by apb and can't be overridden", kv[0])
+		}
+		env = append(env, v1.EnvVar{Name: kv[0], Value: kv[1]})
+	}
+	return env, nil
+}
+
+// mergeEnv appends overrides to base, replacing variables of the same name in place
+func mergeEnv(base []v1.EnvVar, overrides []v1.EnvVar) []v1.EnvVar {
+	merged := append([]v1.EnvVar{}, base...)
+	for _, o := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == o.Name {
+				merged[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// envFromSources exposes every key of the given secrets and configmaps as environment variables
+func envFromSources(secrets []string, configMaps []string) []v1.EnvFromSource {
+	sources := []v1.EnvFromSource{}
+	for _, name := range configMaps {
+		sources = append(sources, v1.EnvFromSource{
+			ConfigMapRef: &v1.ConfigMapEnvSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: name},
+			},
+		})
+	}
+	for _, name := range secrets {
+		sources = append(sources, v1.EnvFromSource{
+			SecretRef: &v1.SecretEnvSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: name},
+			},
+		})
+	}
+	return sources
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+)
+
+func TestParseEnvArgs(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name      string
+		args      []string
+		expected  []v1.EnvVar
+		shouldErr bool
+	}{
+		{
+			name:     "test ordered values",
+			args:     []string{"B=2", "A=1=1", "EMPTY="},
+			expected: []v1.EnvVar{{Name: "B", Value: "2"}, {Name: "A", Value: "1=1"}, {Name: "EMPTY", Value: ""}},
+		},
+		{
+			name:      "test missing value",
+			args:      []string{"A"},
+			shouldErr: true,
+		},
+		{
+			name:      "test missing key",
+			args:      []string{"=1"},
+			shouldErr: true,
+		},
+		{
+			name:      "test reserved variable",
+			args:      []string{"POD_NAME=apb"},
+			shouldErr: true,
+		},
+		{
+			name:      "test reserved state location",
+			args:      []string{"BUNDLE_STATE_LOCATION=/tmp/state"},
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := parseEnvArgs(tc.args)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error parsing %v", tc.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(env, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, env)
+			}
+		})
+	}
+}
+
+func TestCreatePodEnv(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name     string
+		proxy    config.ProxySettings
+		custom   []v1.EnvVar
+		expected map[string]string
+	}{
+		{
+			name:     "test no proxy",
+			expected: map[string]string{},
+		},
+		{
+			name:  "test proxy in both cases",
+			proxy: config.ProxySettings{HTTPProxy: "http://proxy:3128", NoProxy: ".svc"},
+			expected: map[string]string{
+				"HTTP_PROXY": "http://proxy:3128",
+				"http_proxy": "http://proxy:3128",
+				"NO_PROXY":   ".svc",
+				"no_proxy":   ".svc",
+			},
+		},
+		{
+			name:   "test custom env overrides proxy",
+			proxy:  config.ProxySettings{HTTPSProxy: "http://proxy:3128"},
+			custom: []v1.EnvVar{{Name: "https_proxy", Value: "http://other:8080"}, {Name: "DEBUG", Value: "1"}},
+			expected: map[string]string{
+				"HTTPS_PROXY": "http://proxy:3128",
+				"https_proxy": "http://other:8080",
+				"DEBUG":       "1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := createPodEnv(runtime.ExecutionContext{ProxyConfig: proxyConfig(tc.proxy)}, tc.custom)
+			// Without state, the state location isn't set
+			if len(env) != len(tc.expected)+len(reservedEnv)-1 {
+				t.Fatalf("expected %d variables, got %v", len(tc.expected)+len(reservedEnv)-1, env)
+			}
+			for _, e := range env {
+				if contains(reservedEnv, e.Name) {
+					continue
+				}
+				if v, ok := tc.expected[e.Name]; !ok || v != e.Value {
+					t.Fatalf("unexpected variable %v=%v", e.Name, e.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvFromSources(t *testing.T) {
+	sources := envFromSources([]string{"creds"}, []string{"settings"})
+	if len(sources) != 2 {
+		t.Fatalf("expected 2 sources, got %v", sources)
+	}
+	if sources[0].ConfigMapRef == nil || sources[0].ConfigMapRef.Name != "settings" {
+		t.Fatalf("expected configmap settings first, got %v", sources[0])
+	}
+	if sources[1].SecretRef == nil || sources[1].SecretRef.Name != "creds" {
+		t.Fatalf("expected secret creds, got %v", sources[1])
+	}
+}
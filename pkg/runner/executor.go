@@ -0,0 +1,84 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+)
+
+// Executors running APB actions
+const (
+	ExecutorKubernetes = "kubernetes"
+	ExecutorDocker     = "docker"
+	ExecutorPodman     = "podman"
+)
+
+// Executor runs the container of an APB action. Whatever the executor, the APB runs with the
+// environment, extra-vars and sandbox permissions of the pod RunBundle builds for it.
+type Executor interface {
+	// Start starts the APB from the spec of its pod
+	Start(ec runtime.ExecutionContext, pod *v1.Pod, inlineExtraVars bool) error
+	// PrintLogs prints the logs of a started APB, until it finished with opts.Follow
+	PrintLogs(name string, namespace string, opts LogOptions) error
+	// Wait waits for a started APB to finish, failing if it did. Local containers are removed.
+	Wait(name string, namespace string, showProgress bool) (Result, error)
+}
+
+// NewExecutor returns the executor with the given name, defaulting to Kubernetes
+func NewExecutor(name string) (Executor, error) {
+	switch name {
+	case "", ExecutorKubernetes:
+		return kubernetesExecutor{}, nil
+	case ExecutorDocker, ExecutorPodman:
+		return containerExecutor{command: name}, nil
+	}
+	return nil, fmt.Errorf("invalid executor [%v], expected %v, %v or %v", name, ExecutorKubernetes, ExecutorDocker, ExecutorPodman)
+}
+
+// kubernetesExecutor runs APBs as pods in the cluster
+type kubernetesExecutor struct{}
+
+func (kubernetesExecutor) Start(ec runtime.ExecutionContext, pod *v1.Pod, inlineExtraVars bool) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	createdPod, err := k8scli.Client.CoreV1().Pods(ec.Location).Create(pod)
+	if err != nil {
+		return err
+	}
+	if !inlineExtraVars {
+		err = createExtraVarsSecret(createdPod, extraVarsSecretName(ec.BundleName), []byte(ec.ExtraVars))
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Successfully created pod [%v] to %s [%v] in namespace [%v]\n", ec.BundleName, ec.Action, ec.Metadata[BundleFQNameLabel], ec.Location)
+	return nil
+}
+
+func (kubernetesExecutor) PrintLogs(name string, namespace string, opts LogOptions) error {
+	return PrintLogs(name, namespace, opts)
+}
+
+func (kubernetesExecutor) Wait(name string, namespace string, showProgress bool) (Result, error) {
+	return WaitForBundle(name, namespace, showProgress)
+}
@@ -0,0 +1,52 @@
+package runner
+
+import "testing"
+
+func TestNewExecutor(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name      string
+		executor  string
+		expected  Executor
+		shouldErr bool
+	}{
+		{
+			name:     "default",
+			executor: "",
+			expected: kubernetesExecutor{},
+		},
+		{
+			name:     "kubernetes",
+			executor: ExecutorKubernetes,
+			expected: kubernetesExecutor{},
+		},
+		{
+			name:     "podman",
+			executor: ExecutorPodman,
+			expected: containerExecutor{command: "podman"},
+		},
+		{
+			name:      "invalid executor",
+			executor:  "containerd",
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			executor, err := NewExecutor(tc.executor)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected an error for executor [%v]", tc.executor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if executor != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, executor)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// multilineTerminator ends multiline input
+const multilineTerminator = "."
+
+// All prompts read from the same buffered reader so no input is lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine reads a full line of input, including spaces, without the line ending
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// readMultiline reads lines of input until a line containing only multilineTerminator or the end of input
+func readMultiline() (string, error) {
+	lines := []string{}
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if line == multilineTerminator {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// isMultilineParam returns true for parameters entered over several lines
+func isMultilineParam(paramType string, displayType string) bool {
+	return displayType == "textarea" || paramType == "array" || paramType == "object"
+}
+
+// parseStructuredInput parses an array or object parameter value written as JSON or YAML
+func parseStructuredInput(input string, paramType string) (interface{}, error) {
+	var value interface{}
+	err := json.Unmarshal([]byte(input), &value)
+	if err != nil {
+		// JSON is a subset of YAML, so only report the YAML error
+		jsonInput, yamlErr := yaml.YAMLToJSON([]byte(input))
+		if yamlErr != nil {
+			return nil, fmt.Errorf("Input must be a JSON or YAML %v: %v", paramType, yamlErr)
+		}
+		err = json.Unmarshal(jsonInput, &value)
+		if err != nil {
+			return nil, fmt.Errorf("Input must be a JSON or YAML %v: %v", paramType, err)
+		}
+	}
+	switch paramType {
+	case "array":
+		if _, ok := value.([]interface{}); !ok {
+			return nil, fmt.Errorf("Input must be an array, got %v", jsonType(value))
+		}
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("Input must be an object, got %v", jsonType(value))
+		}
+	}
+	return value, nil
+}
+
+// jsonType names the JSON schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,170 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Labels set on every APB action pod
+const (
+	BundleFQNameLabel     = "bundle-fqname"
+	BundleActionLabel     = "bundle-action"
+	BundlePodNameLabel    = "bundle-pod-name"
+	BundleInstanceIDLabel = "bundle-instance-id"
+)
+
+// Instance is an APB instance provisioned in a namespace
+type Instance struct {
+	ID         string
+	BundleName string
+	Namespace  string
+	// Local is true if the instance is recorded in this machine's instances.json
+	Local bool
+	// Phase of the provision pod, empty if it was not found in the cluster
+	Phase string
+	// LastOperation is the last operation the provision APB reported, from its pod or, once the pod
+	// is gone, from this machine's instances.json
+	LastOperation string
+	// TargetNamespaces are the extra namespaces the instance was provisioned into
+	TargetNamespaces []string
+}
+
+// ListInstances returns the instances in a namespace, combining the instances provisioned from this machine
+// with those discovered from provision pods in the cluster. Instances that were deprovisioned successfully
+// are left out. The selector filters instances by the labels of their provision pod.
+func ListInstances(namespace string, selector string) ([]Instance, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector [%v]: %v", selector, err)
+	}
+	var local []config.ProvisionedInstance
+	err = config.ProvisionedInstances.UnmarshalKey("ProvisionedInstances", &local)
+	if err != nil {
+		return nil, err
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := k8scli.Client.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: BundleActionLabel})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list APB pods in namespace [%v]: %v", namespace, err)
+	}
+	return mergeInstances(local, pods.Items, namespace, sel), nil
+}
+
+// mergeInstances combines locally recorded instances with the instances found from APB pods
+func mergeInstances(local []config.ProvisionedInstance, pods []v1.Pod, namespace string, sel labels.Selector) []Instance {
+	instances := map[string]*Instance{}
+	deprovisioned := map[string]bool{}
+	for _, pod := range pods {
+		id := podInstanceID(pod)
+		if id == "" {
+			continue
+		}
+		switch pod.Labels[BundleActionLabel] {
+		case "provision":
+			// Match older pods without the instance ID label as if they had it
+			podLabels := labels.Set{BundleInstanceIDLabel: id}
+			for k, v := range pod.Labels {
+				podLabels[k] = v
+			}
+			if !sel.Matches(podLabels) {
+				continue
+			}
+			instances[id] = &Instance{
+				ID:               id,
+				BundleName:       pod.Labels[BundleFQNameLabel],
+				Namespace:        namespace,
+				Phase:            string(pod.Status.Phase),
+				LastOperation:    pod.Annotations[LastOperationAnnotation],
+				TargetNamespaces: parseTargetNamespaces(pod.Annotations[TargetNamespacesAnnotation]),
+			}
+		case "deprovision":
+			if pod.Status.Phase == v1.PodSucceeded {
+				deprovisioned[id] = true
+			}
+		}
+	}
+	for _, l := range local {
+		for _, id := range l.InstanceIDs[namespace] {
+			if instance, ok := instances[id]; ok {
+				instance.Local = true
+				if len(instance.TargetNamespaces) == 0 {
+					instance.TargetNamespaces = l.TargetNamespaces[id]
+				}
+				if instance.LastOperation == "" {
+					instance.LastOperation = l.LastOperations[id]
+				}
+				continue
+			}
+			// Provision pods may have been cleaned up, match the labels they would have had
+			podLabels := labels.Set{
+				BundleFQNameLabel:     l.BundleName,
+				BundleActionLabel:     "provision",
+				BundleInstanceIDLabel: id,
+			}
+			if !sel.Matches(podLabels) {
+				continue
+			}
+			instances[id] = &Instance{
+				ID:               id,
+				BundleName:       l.BundleName,
+				Namespace:        namespace,
+				Local:            true,
+				LastOperation:    l.LastOperations[id],
+				TargetNamespaces: l.TargetNamespaces[id],
+			}
+		}
+	}
+
+	result := []Instance{}
+	for id, instance := range instances {
+		if !deprovisioned[id] {
+			result = append(result, *instance)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BundleName != result[j].BundleName {
+			return result[i].BundleName < result[j].BundleName
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// podInstanceID returns the instance ID an APB pod acted on. Pods created by older versions have no
+// instance ID label, but their name ends with it.
+func podInstanceID(pod v1.Pod) string {
+	if id, ok := pod.Labels[BundleInstanceIDLabel]; ok {
+		return id
+	}
+	prefix := fmt.Sprintf("bundle-%v-", pod.Labels[BundleActionLabel])
+	if !strings.HasPrefix(pod.Name, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(pod.Name, prefix)
+}
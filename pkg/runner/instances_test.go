@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func bundlePod(name string, podLabels map[string]string, phase v1.PodPhase) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: podLabels},
+		Status:     v1.PodStatus{Phase: phase},
+	}
+}
+
+func TestMergeInstances(t *testing.T) {
+	local := []config.ProvisionedInstance{
+		{BundleName: "postgresql-apb", InstanceIDs: map[string][]string{"apb": {"1111", "2222"}, "other": {"9999"}}},
+	}
+	pods := []v1.Pod{
+		// provisioned from this machine, pod still around
+		bundlePod("bundle-provision-1111", map[string]string{BundleFQNameLabel: "postgresql-apb", BundleActionLabel: "provision"}, v1.PodSucceeded),
+		// provisioned by someone else
+		bundlePod("bundle-provision-3333", map[string]string{BundleFQNameLabel: "mediawiki-apb", BundleActionLabel: "provision", BundleInstanceIDLabel: "3333"}, v1.PodSucceeded),
+		// provisioned and deprovisioned by someone else
+		bundlePod("bundle-provision-4444", map[string]string{BundleFQNameLabel: "mediawiki-apb", BundleActionLabel: "provision"}, v1.PodSucceeded),
+		bundlePod("bundle-deprovision-4444", map[string]string{BundleFQNameLabel: "mediawiki-apb", BundleActionLabel: "deprovision"}, v1.PodSucceeded),
+		// deprovision failed, instance remains
+		bundlePod("bundle-provision-5555", map[string]string{BundleFQNameLabel: "mediawiki-apb", BundleActionLabel: "provision"}, v1.PodSucceeded),
+		bundlePod("bundle-deprovision-5555", map[string]string{BundleFQNameLabel: "mediawiki-apb", BundleActionLabel: "deprovision"}, v1.PodFailed),
+	}
+	// test case table
+	testCases := []struct {
+		name     string
+		selector string
+		ids      []string
+	}{
+		{
+			name:     "test all instances",
+			selector: "",
+			ids:      []string{"3333", "5555", "1111", "2222"},
+		},
+		{
+			name:     "test selector on fqname",
+			selector: "bundle-fqname=postgresql-apb",
+			ids:      []string{"1111", "2222"},
+		},
+		{
+			name:     "test selector on instance id",
+			selector: "bundle-instance-id=3333",
+			ids:      []string{"3333"},
+		},
+		{
+			name:     "test selector on instance id of older pod",
+			selector: "bundle-instance-id=1111",
+			ids:      []string{"1111"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sel, err := labels.Parse(tc.selector)
+			if err != nil {
+				t.Fatalf("unexpected error parsing selector: %v", err)
+			}
+			instances := mergeInstances(local, pods, "apb", sel)
+			if len(instances) != len(tc.ids) {
+				t.Fatalf("expected instances %v, got %v", tc.ids, instances)
+			}
+			for i, id := range tc.ids {
+				if instances[i].ID != id {
+					t.Fatalf("expected instances %v, got %v", tc.ids, instances)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeInstancesLastOperation(t *testing.T) {
+	pod := bundlePod("bundle-provision-1111", map[string]string{BundleFQNameLabel: "postgresql-apb", BundleActionLabel: "provision"}, v1.PodRunning)
+	pod.Annotations = map[string]string{LastOperationAnnotation: "Creating deployment"}
+	instances := mergeInstances(nil, []v1.Pod{pod}, "apb", labels.Everything())
+	if len(instances) != 1 || instances[0].LastOperation != "Creating deployment" {
+		t.Fatalf("expected instance with last operation, got %v", instances)
+	}
+}
+
+func TestMergeInstancesStoredLastOperation(t *testing.T) {
+	local := []config.ProvisionedInstance{
+		{
+			BundleName:     "postgresql-apb",
+			InstanceIDs:    map[string][]string{"apb": {"1111", "2222"}},
+			LastOperations: map[string]string{"1111": "Database ready", "2222": "Stored"},
+		},
+	}
+	pod := bundlePod("bundle-provision-2222", map[string]string{BundleFQNameLabel: "postgresql-apb", BundleActionLabel: "provision"}, v1.PodSucceeded)
+	pod.Annotations = map[string]string{LastOperationAnnotation: "From pod"}
+	instances := mergeInstances(local, []v1.Pod{pod}, "apb", labels.Everything())
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances, got %v", instances)
+	}
+	// The pod of 1111 is gone, the annotation of the pod of 2222 wins
+	if instances[0].LastOperation != "Database ready" || instances[1].LastOperation != "From pod" {
+		t.Fatalf("unexpected last operations %v", instances)
+	}
+}
@@ -0,0 +1,298 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	logReconnectRetries = 5
+	logReconnectDelay   = 3 * time.Second
+)
+
+// fatalWaitReasons are container waiting reasons a pod doesn't recover from by itself
+var fatalWaitReasons = []string{"ImagePullBackOff", "ErrImageNeverPull", "InvalidImageName", "CreateContainerConfigError"}
+
+// LogOptions controls how the logs of an APB pod are printed
+type LogOptions struct {
+	// Follow waits for the pod to start and streams its logs until it finishes
+	Follow bool
+	// Timestamps prefixes every line with its RFC3339 timestamp
+	Timestamps bool
+	// LogFile also appends the logs to a file
+	LogFile string
+}
+
+// PrintLogs prints the logs of an APB pod. When following, problems keeping the pod from
+// starting are reported, and dropped log streams are resumed where they stopped.
+func PrintLogs(podName string, namespace string, opts LogOptions) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	pods := k8scli.Client.CoreV1().Pods(namespace)
+
+	out, closeOut, err := logOutput(opts.LogFile)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+
+	if opts.Follow {
+		err = waitForPodStart(pods, podName, os.Stdout)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pod started. Reading logs...\n")
+	}
+
+	printLogsHeader()
+
+	var since *metav1.Time
+	retries := 0
+	for {
+		last, err := streamLogs(pods, podName, opts, since, out)
+		if last != nil {
+			since = last
+			retries = 0
+		}
+		if !opts.Follow {
+			return err
+		}
+		if err == nil {
+			// The stream also ends when the connection to the kubelet drops
+			pod, err := pods.Get(podName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if podFinished(pod) {
+				return nil
+			}
+		}
+		retries++
+		if retries > logReconnectRetries {
+			return fmt.Errorf("lost the log stream of pod [%v]", podName)
+		}
+		log.Debugf("Log stream of pod [%v] ended: %v", podName, err)
+		time.Sleep(logReconnectDelay)
+	}
+}
+
+// logOutput returns stdout, also appending to the log file if there is one, and the function closing it
+func logOutput(logFile string) (io.Writer, func(), error) {
+	if logFile == "" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open log file [%v]: %v", logFile, err)
+	}
+	return io.MultiWriter(os.Stdout, f), func() { f.Close() }, nil
+}
+
+func printLogsHeader() {
+	fmt.Println("-+- ---------------------- -+-")
+	fmt.Println(" |         APB LOGS         | ")
+	fmt.Println("-+- ---------------------- -+-")
+}
+
+// waitForPodStart watches the pod until its container runs, reporting to out why it is still waiting
+func waitForPodStart(pods corev1.PodInterface, podName string, out io.Writer) error {
+	fmt.Fprintf(out, "Waiting for APB pod [%v] to start...\n", podName)
+	selector := fields.OneTermEqualSelector("metadata.name", podName).String()
+	reported := ""
+	for {
+		w, err := pods.Watch(metav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			return err
+		}
+		for event := range w.ResultChan() {
+			if event.Type == watch.Deleted {
+				w.Stop()
+				return fmt.Errorf("pod [%v] was deleted", podName)
+			}
+			pod, ok := event.Object.(*v1.Pod)
+			if !ok {
+				continue
+			}
+			started, reason, fatal := podStartStatus(pod)
+			if started {
+				w.Stop()
+				return nil
+			}
+			if reason != "" && reason != reported {
+				fmt.Fprintf(out, "APB pod [%v] is not running: %v\n", podName, reason)
+				reported = reason
+			}
+			if fatal {
+				w.Stop()
+				return fmt.Errorf("APB pod [%v] can't start: %v", podName, reason)
+			}
+		}
+		// Watches time out, start a new one
+		w.Stop()
+		time.Sleep(time.Second)
+	}
+}
+
+// podStartStatus tells whether the pod has started, and otherwise why it is waiting and whether
+// it will stay stuck
+func podStartStatus(pod *v1.Pod) (started bool, reason string, fatal bool) {
+	switch pod.Status.Phase {
+	case v1.PodRunning, v1.PodSucceeded, v1.PodFailed:
+		return true, "", false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		waiting := status.State.Waiting
+		if waiting == nil || waiting.Reason == "" || waiting.Reason == "ContainerCreating" {
+			continue
+		}
+		reason = waiting.Reason
+		if waiting.Message != "" {
+			reason = fmt.Sprintf("%v: %v", waiting.Reason, waiting.Message)
+		}
+		return false, reason, contains(fatalWaitReasons, waiting.Reason)
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse && cond.Reason != "" {
+			reason = cond.Reason
+			if cond.Message != "" {
+				reason = fmt.Sprintf("%v: %v", cond.Reason, cond.Message)
+			}
+			return false, reason, false
+		}
+	}
+	return false, "", false
+}
+
+// podFinished tells whether the APB container has exited
+func podFinished(pod *v1.Pod) bool {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return true
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil {
+			return false
+		}
+	}
+	return len(pod.Status.ContainerStatuses) > 0
+}
+
+// streamLogs copies the pod logs after since to out, and returns the timestamp of the last line
+// written. Logs are always requested with timestamps so that a dropped stream can be resumed.
+func streamLogs(pods corev1.PodInterface, podName string, opts LogOptions, since *metav1.Time, out io.Writer) (*metav1.Time, error) {
+	stream, err := pods.GetLogs(podName, &v1.PodLogOptions{
+		Follow:     opts.Follow,
+		Timestamps: true,
+		SinceTime:  since,
+	}).Stream()
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var last *metav1.Time
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		// A partial line is only complete at the end of the logs, otherwise it is read again
+		// when the stream is resumed
+		if line != "" && (err == nil || err == io.EOF) {
+			ts, text := splitTimestamp(line)
+			if ts == nil || since == nil || ts.After(since.Time) {
+				if opts.Timestamps {
+					text = line
+				}
+				fmt.Fprint(out, text)
+				if ts != nil {
+					last = &metav1.Time{Time: *ts}
+				}
+			}
+		}
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return last, err
+		}
+	}
+}
+
+// splitTimestamp splits a log line requested with timestamps into its timestamp and text
+func splitTimestamp(line string) (*time.Time, string) {
+	i := strings.Index(line, " ")
+	if i < 0 {
+		return nil, line
+	}
+	ts, err := time.Parse(time.RFC3339Nano, line[:i])
+	if err != nil {
+		return nil, line
+	}
+	return &ts, line[i+1:]
+}
+
+// FindActionPods returns the pod with the given name, or else the action pods of the instance
+// with the given ID, oldest first
+func FindActionPods(namespace string, nameOrID string) ([]v1.Pod, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	pod, err := k8scli.Client.CoreV1().Pods(namespace).Get(nameOrID, metav1.GetOptions{})
+	if err == nil {
+		return []v1.Pod{*pod}, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	pods, err := k8scli.Client.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: BundleActionLabel})
+	if err != nil {
+		return nil, err
+	}
+	return instancePods(pods.Items, nameOrID), nil
+}
+
+// instancePods returns the pods of an instance, oldest first
+func instancePods(pods []v1.Pod, id string) []v1.Pod {
+	matching := []v1.Pod{}
+	for _, pod := range pods {
+		if podInstanceID(pod) == id {
+			matching = append(matching, pod)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].CreationTimestamp.Before(&matching[j].CreationTimestamp)
+	})
+	return matching
+}
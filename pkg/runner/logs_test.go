@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodStartStatus(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name    string
+		status  v1.PodStatus
+		started bool
+		reason  string
+		fatal   bool
+	}{
+		{
+			name:    "test running",
+			status:  v1.PodStatus{Phase: v1.PodRunning},
+			started: true,
+		},
+		{
+			name: "test container creating",
+			status: v1.PodStatus{Phase: v1.PodPending, ContainerStatuses: []v1.ContainerStatus{
+				{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+			}},
+		},
+		{
+			name: "test image pull backoff",
+			status: v1.PodStatus{Phase: v1.PodPending, ContainerStatuses: []v1.ContainerStatus{
+				{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+			}},
+			reason: "ImagePullBackOff: Back-off pulling image",
+			fatal:  true,
+		},
+		{
+			name: "test image pull error",
+			status: v1.PodStatus{Phase: v1.PodPending, ContainerStatuses: []v1.ContainerStatus{
+				{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+			}},
+			reason: "ErrImagePull",
+		},
+		{
+			name: "test unschedulable",
+			status: v1.PodStatus{Phase: v1.PodPending, Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available"},
+			}},
+			reason: "Unschedulable: 0/3 nodes are available",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			started, reason, fatal := podStartStatus(&v1.Pod{Status: tc.status})
+			if started != tc.started || reason != tc.reason || fatal != tc.fatal {
+				t.Fatalf("expected (%v, %q, %v), got (%v, %q, %v)", tc.started, tc.reason, tc.fatal, started, reason, fatal)
+			}
+		})
+	}
+}
+
+func TestSplitTimestamp(t *testing.T) {
+	ts, text := splitTimestamp("2018-10-19T10:20:30.123456789Z TASK [create deployment]\n")
+	if ts == nil || !ts.Equal(time.Date(2018, 10, 19, 10, 20, 30, 123456789, time.UTC)) {
+		t.Fatalf("unexpected timestamp %v", ts)
+	}
+	if text != "TASK [create deployment]\n" {
+		t.Fatalf("unexpected text %q", text)
+	}
+	ts, text = splitTimestamp("no timestamp here\n")
+	if ts != nil || text != "no timestamp here\n" {
+		t.Fatalf("expected line without timestamp, got %v %q", ts, text)
+	}
+}
+
+func TestInstancePods(t *testing.T) {
+	newPod := func(name, action, id string, created int64) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{BundleActionLabel: action, BundleInstanceIDLabel: id},
+			CreationTimestamp: metav1.Unix(created, 0),
+		}}
+	}
+	pods := []v1.Pod{
+		newPod("bundle-deprovision-1", "deprovision", "1", 20),
+		newPod("bundle-provision-2", "provision", "2", 5),
+		newPod("bundle-provision-1", "provision", "1", 10),
+	}
+	matching := instancePods(pods, "1")
+	if len(matching) != 2 || matching[0].Name != "bundle-provision-1" || matching[1].Name != "bundle-deprovision-1" {
+		t.Fatalf("expected provision then deprovision pod of instance 1, got %v", matching)
+	}
+}
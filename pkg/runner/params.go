@@ -0,0 +1,115 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/clients"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Prefixes of parameter values that are resolved at runtime instead of taken literally
+const (
+	secretRefPrefix = "@secret:"
+	fileRefPrefix   = "@file:"
+	envRefPrefix    = "@env:"
+)
+
+// redactedValue replaces sensitive parameter values in output
+const redactedValue = "<redacted>"
+
+// parseParamArgs parses name=value parameter arguments
+func parseParamArgs(paramArgs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, arg := range paramArgs {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid parameter [%v], expected name=value", arg)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
+
+// isParamRef returns true if a parameter value refers to a secret, file or environment variable
+func isParamRef(value string) bool {
+	return strings.HasPrefix(value, secretRefPrefix) ||
+		strings.HasPrefix(value, fileRefPrefix) ||
+		strings.HasPrefix(value, envRefPrefix)
+}
+
+// resolveParamValue returns the value a parameter value refers to, or the value itself if it is not a reference
+func resolveParamValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretRefPrefix):
+		ref := strings.TrimPrefix(value, secretRefPrefix)
+		parts := strings.Split(ref, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return "", fmt.Errorf("invalid secret reference [%v], expected %vnamespace/secret/key", value, secretRefPrefix)
+		}
+		return readSecretKey(parts[0], parts[1], parts[2])
+	case strings.HasPrefix(value, fileRefPrefix):
+		path := strings.TrimPrefix(value, fileRefPrefix)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read parameter value from file: %v", err)
+		}
+		return strings.TrimSuffix(string(data), "\n"), nil
+	case strings.HasPrefix(value, envRefPrefix):
+		name := strings.TrimPrefix(value, envRefPrefix)
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable [%v] is not set", name)
+		}
+		return envValue, nil
+	}
+	return value, nil
+}
+
+func readSecretKey(namespace string, secretName string, key string) (string, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return "", err
+	}
+	secret, err := k8scli.Client.CoreV1().Secrets(namespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret [%v/%v]: %v", namespace, secretName, err)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret [%v/%v] has no key [%v]", namespace, secretName, key)
+	}
+	return string(value), nil
+}
+
+// redactParams returns a copy of params with the sensitive values replaced, safe for logging
+func redactParams(params bundle.Parameters, sensitive map[string]bool) bundle.Parameters {
+	redacted := bundle.Parameters{}
+	for name, value := range params {
+		if sensitive[name] {
+			redacted[name] = redactedValue
+		} else {
+			redacted[name] = value
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func TestResolveParamValue(t *testing.T) {
+	f, err := ioutil.TempFile("", "apb-param")
+	if err != nil {
+		t.Fatalf("unable to create temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("file-secret\n")
+	f.Close()
+	os.Setenv("APB_TEST_PARAM", "env-secret")
+	defer os.Unsetenv("APB_TEST_PARAM")
+
+	// test case table
+	testCases := []struct {
+		name      string
+		value     string
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:     "test literal value",
+			value:    "plain",
+			expected: "plain",
+		},
+		{
+			name:     "test file reference",
+			value:    "@file:" + f.Name(),
+			expected: "file-secret",
+		},
+		{
+			name:     "test env reference",
+			value:    "@env:APB_TEST_PARAM",
+			expected: "env-secret",
+		},
+		{
+			name:      "test unset env reference",
+			value:     "@env:APB_TEST_PARAM_UNSET",
+			shouldErr: true,
+		},
+		{
+			name:      "test missing file reference",
+			value:     "@file:/nonexistent/apb-param",
+			shouldErr: true,
+		},
+		{
+			name:      "test malformed secret reference",
+			value:     "@secret:ns/secret",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := resolveParamValue(tc.value)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error resolving [%v]", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving [%v]: %v", tc.value, err)
+			}
+			if value != tc.expected {
+				t.Fatalf("expected [%v], got [%v]", tc.expected, value)
+			}
+		})
+	}
+}
+
+func TestRedactParams(t *testing.T) {
+	params := bundle.Parameters{"user": "admin", "password": "hunter2"}
+	sensitive := map[string]bool{"password": true}
+
+	redacted := redactParams(params, sensitive)
+	if redacted["password"] != redactedValue || redacted["user"] != "admin" {
+		t.Fatalf("expected only password to be redacted, got %v", redacted)
+	}
+	if params["password"] != "hunter2" {
+		t.Fatalf("expected redactParams to leave params unchanged, got %v", params)
+	}
+}
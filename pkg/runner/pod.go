@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultImagePullPolicy is used when no pull policy is configured
+const defaultImagePullPolicy = v1.PullAlways
+
+// applyPodSettings applies the configured pull policy, resources, scheduling constraints, security
+// context, labels and annotations to an APB pod
+func applyPodSettings(pod *v1.Pod, settings config.PodSettings) error {
+	container := &pod.Spec.Containers[0]
+
+	switch v1.PullPolicy(settings.ImagePullPolicy) {
+	case "":
+		container.ImagePullPolicy = defaultImagePullPolicy
+	case v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+		container.ImagePullPolicy = v1.PullPolicy(settings.ImagePullPolicy)
+	default:
+		return fmt.Errorf("invalid image pull policy [%v], expected Always, IfNotPresent or Never", settings.ImagePullPolicy)
+	}
+
+	requests, err := resourceList(settings.CPURequest, settings.MemoryRequest)
+	if err != nil {
+		return err
+	}
+	limits, err := resourceList(settings.CPULimit, settings.MemoryLimit)
+	if err != nil {
+		return err
+	}
+	container.Resources = v1.ResourceRequirements{Requests: requests, Limits: limits}
+
+	nodeSelector, err := parseKeyValues(settings.NodeSelector)
+	if err != nil {
+		return fmt.Errorf("invalid node selector: %v", err)
+	}
+	if len(nodeSelector) > 0 {
+		pod.Spec.NodeSelector = nodeSelector
+	}
+	for _, t := range settings.Tolerations {
+		toleration, err := parseToleration(t)
+		if err != nil {
+			return err
+		}
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, toleration)
+	}
+
+	if settings.RunAsUser != nil || settings.RunAsNonRoot || settings.FSGroup != nil {
+		pod.Spec.SecurityContext = &v1.PodSecurityContext{
+			RunAsUser: settings.RunAsUser,
+			FSGroup:   settings.FSGroup,
+		}
+		if settings.RunAsNonRoot {
+			runAsNonRoot := true
+			pod.Spec.SecurityContext.RunAsNonRoot = &runAsNonRoot
+		}
+	}
+
+	labels, err := parseKeyValues(settings.Labels)
+	if err != nil {
+		return fmt.Errorf("invalid pod label: %v", err)
+	}
+	for key, value := range labels {
+		// The bundle labels identify the pod, don't let them be replaced
+		if _, ok := pod.Labels[key]; ok {
+			return fmt.Errorf("pod label [%v] is reserved", key)
+		}
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[key] = value
+	}
+	annotations, err := parseKeyValues(settings.Annotations)
+	if err != nil {
+		return fmt.Errorf("invalid pod annotation: %v", err)
+	}
+	if len(annotations) > 0 {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		for key, value := range annotations {
+			pod.Annotations[key] = value
+		}
+	}
+	return nil
+}
+
+func resourceList(cpu string, memory string) (v1.ResourceList, error) {
+	resources := v1.ResourceList{}
+	if cpu != "" {
+		quantity, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU quantity [%v]: %v", cpu, err)
+		}
+		resources[v1.ResourceCPU] = quantity
+	}
+	if memory != "" {
+		quantity, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory quantity [%v]: %v", memory, err)
+		}
+		resources[v1.ResourceMemory] = quantity
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+	return resources, nil
+}
+
+// parseKeyValues parses key=value strings into a map
+func parseKeyValues(pairs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("[%v] is not in key=value form", pair)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
+
+// parseToleration parses a toleration written like a taint: key[=value]:effect, optionally followed
+// by :seconds for NoExecute. An empty effect tolerates all effects.
+func parseToleration(t string) (v1.Toleration, error) {
+	parts := strings.Split(t, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return v1.Toleration{}, fmt.Errorf("invalid toleration [%v], expected key[=value]:effect[:seconds]", t)
+	}
+	toleration := v1.Toleration{Operator: v1.TolerationOpExists}
+	keyValue := strings.SplitN(parts[0], "=", 2)
+	toleration.Key = keyValue[0]
+	if len(keyValue) == 2 {
+		toleration.Operator = v1.TolerationOpEqual
+		toleration.Value = keyValue[1]
+	}
+	switch v1.TaintEffect(parts[1]) {
+	case "", v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		toleration.Effect = v1.TaintEffect(parts[1])
+	default:
+		return v1.Toleration{}, fmt.Errorf("invalid toleration effect [%v], expected NoSchedule, PreferNoSchedule or NoExecute", parts[1])
+	}
+	if len(parts) == 3 {
+		if toleration.Effect != v1.TaintEffectNoExecute {
+			return v1.Toleration{}, fmt.Errorf("invalid toleration [%v], seconds only apply to NoExecute", t)
+		}
+		seconds, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return v1.Toleration{}, fmt.Errorf("invalid toleration seconds [%v]", parts[2])
+		}
+		toleration.TolerationSeconds = &seconds
+	}
+	return toleration, nil
+}
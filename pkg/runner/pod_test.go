@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseToleration(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name       string
+		toleration string
+		expected   v1.Toleration
+		shouldErr  bool
+	}{
+		{
+			name:       "test key and value",
+			toleration: "dedicated=apb:NoSchedule",
+			expected:   v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "apb", Effect: v1.TaintEffectNoSchedule},
+		},
+		{
+			name:       "test key only",
+			toleration: "dedicated:PreferNoSchedule",
+			expected:   v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectPreferNoSchedule},
+		},
+		{
+			name:       "test all effects",
+			toleration: "dedicated:",
+			expected:   v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpExists},
+		},
+		{
+			name:       "test invalid effect",
+			toleration: "dedicated=apb:Never",
+			shouldErr:  true,
+		},
+		{
+			name:       "test missing effect",
+			toleration: "dedicated=apb",
+			shouldErr:  true,
+		},
+		{
+			name:       "test seconds without NoExecute",
+			toleration: "dedicated=apb:NoSchedule:60",
+			shouldErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			toleration, err := parseToleration(tc.toleration)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error parsing [%v]", tc.toleration)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing [%v]: %v", tc.toleration, err)
+			}
+			if toleration != tc.expected {
+				t.Fatalf("expected toleration %+v, got %+v", tc.expected, toleration)
+			}
+		})
+	}
+}
+
+func TestApplyPodSettings(t *testing.T) {
+	runAsUser := int64(1001)
+	// test case table
+	testCases := []struct {
+		name      string
+		settings  config.PodSettings
+		shouldErr bool
+	}{
+		{
+			name:     "test defaults",
+			settings: config.PodSettings{},
+		},
+		{
+			name: "test all settings",
+			settings: config.PodSettings{
+				ImagePullPolicy: "IfNotPresent",
+				CPURequest:      "100m",
+				MemoryLimit:     "512Mi",
+				NodeSelector:    []string{"region=infra"},
+				Tolerations:     []string{"dedicated=apb:NoSchedule"},
+				RunAsUser:       &runAsUser,
+				RunAsNonRoot:    true,
+				Labels:          []string{"team=db"},
+				Annotations:     []string{"owner=db-team"},
+			},
+		},
+		{
+			name:      "test invalid pull policy",
+			settings:  config.PodSettings{ImagePullPolicy: "Sometimes"},
+			shouldErr: true,
+		},
+		{
+			name:      "test invalid quantity",
+			settings:  config.PodSettings{CPULimit: "lots"},
+			shouldErr: true,
+		},
+		{
+			name:      "test reserved label",
+			settings:  config.PodSettings{Labels: []string{"bundle-action=test"}},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{BundleActionLabel: "provision"}},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "apb"}}},
+			}
+			err := applyPodSettings(pod, tc.settings)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("expected error applying %+v", tc.settings)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			container := pod.Spec.Containers[0]
+			if tc.settings.ImagePullPolicy == "" && container.ImagePullPolicy != v1.PullAlways {
+				t.Fatalf("expected default pull policy Always, got [%v]", container.ImagePullPolicy)
+			}
+			if tc.settings.CPURequest != "" {
+				cpu := container.Resources.Requests[v1.ResourceCPU]
+				if cpu.String() != tc.settings.CPURequest {
+					t.Fatalf("expected CPU request [%v], got [%v]", tc.settings.CPURequest, cpu.String())
+				}
+			}
+			if len(tc.settings.Labels) > 0 && pod.Labels["team"] != "db" {
+				t.Fatalf("expected extra label, got %v", pod.Labels)
+			}
+			if tc.settings.RunAsNonRoot && (pod.Spec.SecurityContext == nil || !*pod.Spec.SecurityContext.RunAsNonRoot) {
+				t.Fatalf("expected security context, got %v", pod.Spec.SecurityContext)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"golang.org/x/crypto/ssh/terminal"
+	"k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LastOperationAnnotation is updated by apb-base with a description of what the playbook is doing
+	LastOperationAnnotation = "apb_last_operation"
+	dashboardURLAnnotation  = "apb_dashboard_url"
+)
+
+// Result is the outcome of an APB action
+type Result struct {
+	// Description is the last operation reported by the APB
+	Description  string
+	DashboardURL string
+}
+
+// WaitForBundle watches an APB pod until it finishes. With showProgress set, the last operation
+// reported by the APB is shown while it runs. It is safe to call from several goroutines.
+func WaitForBundle(podName string, namespace string, showProgress bool) (Result, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return Result{}, err
+	}
+	pods := k8scli.Client.CoreV1().Pods(namespace)
+	// Fail early on pods that can't start, which never reach the failed phase
+	out := ioutil.Discard
+	if showProgress {
+		out = os.Stdout
+	}
+	err = waitForPodStart(pods, podName, out)
+	if err != nil {
+		return Result{}, err
+	}
+	provider := currentProvider()
+
+	result := Result{}
+	status := newStatusLine(os.Stdout, showProgress && terminal.IsTerminal(int(os.Stdout.Fd())))
+	update := func(description string, dashboardURL string) {
+		if description != "" && description != result.Description {
+			result.Description = description
+			if showProgress {
+				status.update(description)
+			}
+		}
+		if dashboardURL != "" {
+			result.DashboardURL = dashboardURL
+		}
+	}
+	for {
+		err = provider.WatchRunningBundle(podName, namespace, update)
+		if err != nil {
+			break
+		}
+		// The watch also ends when it times out on the server
+		var pod *v1.Pod
+		pod, err = pods.Get(podName, metav1.GetOptions{})
+		if err != nil {
+			break
+		}
+		if podFinished(pod) {
+			result.DashboardURL = pod.Annotations[dashboardURLAnnotation]
+			if d := pod.Annotations[LastOperationAnnotation]; d != "" {
+				result.Description = d
+			}
+			if pod.Status.Phase == v1.PodFailed {
+				err = fmt.Errorf("pod [%v] failed", podName)
+			}
+			break
+		}
+	}
+	status.done()
+	return result, err
+}
+
+// statusLine shows progress updates, rewriting a single line when live and printing one line
+// per update otherwise
+type statusLine struct {
+	out     io.Writer
+	live    bool
+	printed bool
+}
+
+func newStatusLine(out io.Writer, live bool) *statusLine {
+	return &statusLine{out: out, live: live}
+}
+
+func (s *statusLine) update(text string) {
+	if s.live {
+		// Return to the start of the line and clear it
+		fmt.Fprintf(s.out, "\r\x1b[K%v", text)
+	} else {
+		fmt.Fprintf(s.out, "%v\n", text)
+	}
+	s.printed = true
+}
+
+// done ends a live status line so that the next output starts on its own line
+func (s *statusLine) done() {
+	if s.live && s.printed {
+		fmt.Fprintln(s.out)
+	}
+	s.printed = false
+}
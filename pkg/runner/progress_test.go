@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStatusLine(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name     string
+		live     bool
+		updates  []string
+		expected string
+	}{
+		{
+			name:     "test live line",
+			live:     true,
+			updates:  []string{"Creating service", "Creating deployment"},
+			expected: "\r\x1b[KCreating service\r\x1b[KCreating deployment\n",
+		},
+		{
+			name:     "test one line per update",
+			updates:  []string{"Creating service", "Creating deployment"},
+			expected: "Creating service\nCreating deployment\n",
+		},
+		{
+			name:     "test no updates",
+			live:     true,
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			status := newStatusLine(out, tc.live)
+			for _, u := range tc.updates {
+				status.update(u)
+			}
+			status.done()
+			if out.String() != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, out.String())
+			}
+		})
+	}
+}
@@ -21,10 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
+	"strings"
 	"syscall"
-	"time"
 
 	"github.com/automationbroker/apb/pkg/config"
 	"github.com/automationbroker/bundle-lib/bundle"
@@ -39,14 +38,92 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Mount location and key of the secret holding the extra-vars in APB pods
+const (
+	extraVarsMountPath = "/etc/apb-extra-vars"
+	extraVarsKey       = "extra-vars.json"
+)
+
+// RunOptions holds optional settings for RunBundle
+type RunOptions struct {
+	// Params are parameter values given as name=value. Values can refer to a secret with
+	// @secret:namespace/secret/key, a file with @file:/path or an environment variable with @env:VAR.
+	Params []string
+	// SensitiveParams names parameters of Params whose values must not be shown, like password parameters
+	SensitiveParams []string
+	// TargetNamespaces are namespaces the APB acts on besides the namespace it runs in. Its sandbox
+	// role is granted in each of them. Deprovision defaults to the targets recorded at provision.
+	TargetNamespaces []string
+	// SandboxNamespace runs the APB pod in a transient namespace, named after the APB with AutoSandboxNamespace
+	// or starting with the given name otherwise. The namespace is deleted once the APB finished.
+	SandboxNamespace string
+	// Executor runs the APB: ExecutorKubernetes, the default, or ExecutorDocker and ExecutorPodman
+	// to run it in a local container
+	Executor string
+	// DryRun prints the objects that would be created instead of running the APB: DryRunClient, or
+	// DryRunServer to also submit them to the API server with dryRun
+	DryRun string
+	// KeepNamespaceOnError keeps the transient namespace of a failed APB for debugging
+	KeepNamespaceOnError bool
+	// StateNamespace keeps the state APBs save between actions. It defaults to the namespace of the instance.
+	StateNamespace string
+	// Plan is the plan to run. When empty, it comes from the answers file or is selected interactively.
+	Plan string
+	// AnswersFile replays the plan and parameters saved with SaveAnswersFile. Params take precedence over it.
+	AnswersFile string
+	// SaveAnswersFile records the chosen plan and parameters
+	SaveAnswersFile string
+	// InstanceID is the instance to deprovision. When empty, the instance is selected interactively.
+	InstanceID string
+	// Pod customizes the APB pod
+	Pod config.PodSettings
+	// InlineExtraVars passes the extra-vars as a container argument instead of through a secret,
+	// for APB images whose apb-base doesn't accept '--extra-vars @file'
+	InlineExtraVars bool
+	// Env are extra KEY=VALUE environment variables for the APB pod. They take precedence over the proxy variables.
+	Env []string
+	// EnvFromSecrets and EnvFromConfigMaps expose all keys of secrets and configmaps in the
+	// target namespace as environment variables
+	EnvFromSecrets    []string
+	EnvFromConfigMaps []string
+	// Proxy sets HTTP_PROXY, HTTPS_PROXY and NO_PROXY in the APB pod
+	Proxy config.ProxySettings
+	// Logs controls how the APB pod logs are printed when following them. Setting a log file follows them.
+	Logs LogOptions
+	// Wait waits for the APB to finish, showing its progress. Following the logs also waits.
+	Wait bool
+	// Finished is called with the result of an APB that apb waited for and that succeeded
+	Finished func(Result)
+}
+
+// SandboxError is returned by RunBundle when the sandbox of the APB pod can't be created
+type SandboxError struct {
+	PodName string
+	Err     error
+}
+
+func (e SandboxError) Error() string {
+	return fmt.Sprintf("unable to create sandbox for [%v]: %v", e.PodName, e.Err)
+}
+
 // RunBundle will run the bundle's action in the given namespace
-func RunBundle(action string, ns string, bundleName string, sandboxRole string, bundleRegistry string, printLogs bool, skipParams bool, args []string) (podName string, err error) {
+func RunBundle(action string, ns string, bundleName string, sandboxRole string, bundleRegistry string, printLogs bool, skipParams bool, opts RunOptions) (podName string, err error) {
 	reg := []config.Registry{}
 	var id string
 	var targetSpec *bundle.Spec
 	var candidateSpecs []*bundle.Spec
 
-	if action == "deprovision" {
+	err = validateDryRun(opts.DryRun, opts.SandboxNamespace)
+	if err != nil {
+		return "", err
+	}
+	executor, err := NewExecutor(opts.Executor)
+	if err != nil {
+		return "", err
+	}
+	if action == "deprovision" && opts.InstanceID != "" {
+		id = opts.InstanceID
+	} else if action == "deprovision" {
 		id, err = getProvisionedInstanceId(bundleName, ns)
 		if err != nil {
 			return "", err
@@ -81,98 +158,300 @@ func RunBundle(action string, ns string, bundleName string, sandboxRole string,
 
 	targetSpec = candidateSpecs[0]
 
+	var answers *Answers
+	if opts.AnswersFile != "" {
+		answers, err = loadAnswers(opts.AnswersFile)
+		if err != nil {
+			return "", err
+		}
+		if answers.Bundle != "" && answers.Bundle != bundleName {
+			log.Warningf("Answers file was saved for APB [%v], not [%v]", answers.Bundle, bundleName)
+		}
+	}
+
 	// determine the correct plan
-	plan := selectPlan(targetSpec)
+	var plan bundle.Plan
+	if opts.Plan != "" {
+		plan, err = findPlan(targetSpec, opts.Plan)
+		if err != nil {
+			return "", err
+		}
+	} else if answers != nil && answers.Plan != "" {
+		plan, err = findPlan(targetSpec, answers.Plan)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		plan = selectPlan(targetSpec)
+	}
 	if plan.Name == "" {
 		log.Warning("Did not find a selected plan")
 	} else {
 		fmt.Printf("Plan: %v\n", plan.Name)
 	}
 
-	var params bundle.Parameters
-	if skipParams {
-		params = bundle.Parameters{}
-	} else {
-		params, err = selectParameters(plan)
+	providedParams := map[string]string{}
+	if answers != nil {
+		added, removed := compareAnswerParameters(answers, plan)
+		if len(added) > 0 {
+			fmt.Printf("Parameters added to plan [%v] since the answers were saved: %v\n", plan.Name, strings.Join(added, ", "))
+		}
+		if len(removed) > 0 {
+			fmt.Printf("Parameters removed from plan [%v] since the answers were saved, ignoring them: %v\n", plan.Name, strings.Join(removed, ", "))
+		}
+		providedParams, err = answerInputs(answers, plan)
 		if err != nil {
 			return "", err
 		}
 	}
+	paramArgs, err := parseParamArgs(opts.Params)
+	if err != nil {
+		return "", err
+	}
+	for name, value := range paramArgs {
+		providedParams[name] = value
+	}
+	params, sensitiveParams, err := selectParameters(plan, providedParams, opts.SensitiveParams, !skipParams)
+	if err != nil {
+		return "", err
+	}
+	if opts.SaveAnswersFile != "" {
+		err = saveAnswers(opts.SaveAnswersFile, bundleName, plan, params, sensitiveParams, providedParams)
+		if err != nil {
+			return "", fmt.Errorf("unable to save answers: %v", err)
+		}
+		fmt.Printf("Saved answers to [%v]\n", opts.SaveAnswersFile)
+	}
 
-	extraVars, err := createExtraVars(id, ns, &params, plan)
+	extraTargets := opts.TargetNamespaces
+	if action == "deprovision" && len(extraTargets) == 0 {
+		extraTargets, err = recordedTargets(ns, id)
+		if err != nil {
+			return "", err
+		}
+		if len(extraTargets) > 0 {
+			fmt.Printf("Target namespaces recorded at provision: %v\n", strings.Join(extraTargets, ", "))
+		}
+	}
+	targets := targetNamespaces(ns, extraTargets)
+	err = checkTargetAccess(targets)
 	if err != nil {
 		return "", err
 	}
 
+	extraVars, err := createExtraVars(id, ns, targets, &params, plan)
+	if err != nil {
+		return "", err
+	}
+	if opts.InlineExtraVars && len(sensitiveParams) > 0 {
+		log.Warning("Passing extra-vars inline, sensitive parameters will be visible to anyone who can read the APB pod")
+	}
+
 	labels := map[string]string{
-		"bundle-fqname":   targetSpec.FQName,
-		"bundle-action":   action,
-		"bundle-pod-name": podName,
+		BundleFQNameLabel:     targetSpec.FQName,
+		BundleActionLabel:     action,
+		BundlePodNameLabel:    podName,
+		BundleInstanceIDLabel: id,
+	}
+
+	// Catch invalid pod settings before creating the sandbox
+	podLabels := map[string]string{}
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+	err = applyPodSettings(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{}}},
+	}, opts.Pod)
+	if err != nil {
+		return "", err
+	}
+	customEnv, err := parseEnvArgs(opts.Env)
+	if err != nil {
+		return "", err
 	}
 
 	// TODO: using edit directly. The bundle code uses clusterConfig.SandboxRole
 	// which is defined by the template. So far we've been using edit.
 
-	runtime.NewRuntime(runtime.Configuration{})
-	targets := []string{ns}
-	serviceAccount, namespace, err := runtime.Provider.CreateSandbox(podName, ns, targets, sandboxRole, labels)
+	newStateRuntime(stateNamespace(opts.StateNamespace, ns))
+	sandboxNamespace := ns
+	if opts.SandboxNamespace != "" {
+		sandboxNamespace = sandboxNamespacePrefix(opts.SandboxNamespace, targetSpec.FQName, action)
+	}
+	if opts.DryRun != "" {
+		// Sensitive parameters are redacted from the printed extra-vars
+		redacted := redactParams(params, sensitiveParams)
+		dryRunVars, err := createExtraVars(id, ns, targets, &redacted, plan)
+		if err != nil {
+			return "", err
+		}
+		ec := runtime.ExecutionContext{
+			BundleName:  podName,
+			Targets:     targets,
+			Metadata:    labels,
+			Action:      action,
+			Image:       targetSpec.Image,
+			Account:     podName,
+			Location:    sandboxNamespace,
+			ExtraVars:   dryRunVars,
+			ProxyConfig: proxyConfig(opts.Proxy),
+		}
+		return podName, dryRunBundle(ec, ns, id, sandboxRole, customEnv, opts)
+	}
+	serviceAccount, namespace, err := runtime.Provider.CreateSandbox(podName, sandboxNamespace, targets, sandboxRole, labels)
 	if err != nil {
-		fmt.Printf("\nProblem creating sandbox [%s] to run APB. Did you run `oc new-project %s` first?\n\n", podName, ns)
-		log.Errorf("error creating sandbox: %v", err)
-		os.Exit(-1)
+		return "", SandboxError{PodName: podName, Err: err}
 	}
 
 	ec := runtime.ExecutionContext{
-		BundleName: podName,
-		Targets:    targets,
-		Metadata:   labels,
-		Action:     action,
-		Image:      targetSpec.Image,
-		Account:    serviceAccount,
-		Location:   namespace,
-		ExtraVars:  extraVars,
+		BundleName:  podName,
+		Targets:     targets,
+		Metadata:    labels,
+		Action:      action,
+		Image:       targetSpec.Image,
+		Account:     serviceAccount,
+		Location:    namespace,
+		ExtraVars:   extraVars,
+		ProxyConfig: proxyConfig(opts.Proxy),
+	}
+	transient := ec.Location != ns
+	if transient {
+		fmt.Printf("Running APB in transient namespace [%v]\n", ec.Location)
+		defer destroySandbox(ec, ns, opts.KeepNamespaceOnError)
+		err = copyEnvSources(ec, ns, opts.EnvFromSecrets, opts.EnvFromConfigMaps)
+		if err != nil {
+			return "", err
+		}
+	}
+	// Deprovisions apb didn't wait for leave state behind
+	err = collectDeprovisionedState(ns)
+	if err != nil {
+		log.Warningf("Unable to clean up the state of deprovisioned instances: %v", err)
+	}
+	// Later actions read the state saved by earlier ones
+	if action != "provision" {
+		err = collectProvisionState(ns, id)
+		if err != nil {
+			return "", err
+		}
+		ec.StateName, err = prepareState(ec, id)
+		if err != nil {
+			return "", err
+		}
+		if ec.StateName != "" {
+			ec.StateLocation = runtime.Provider.MountLocation()
+		}
 	}
 
-	k8scli, err := clients.Kubernetes()
+	pod, err := newBundlePod(ec, customEnv, opts)
 	if err != nil {
-		// TODO: return err
-		panic(err.Error())
+		return "", err
 	}
+	err = executor.Start(ec, pod, opts.InlineExtraVars)
+	if err != nil {
+		return "", err
+	}
+
+	following := printLogs || opts.Logs.LogFile != ""
+	if following {
+		logOpts := opts.Logs
+		logOpts.Follow = true
+		err = executor.PrintLogs(podName, ec.Location, logOpts)
+		if err != nil {
+			log.Errorf("Failed to print logs of APB %v pod [%v]: %v", action, podName, err)
+		}
+	}
+	// A transient namespace is only deleted once the APB finished, and so are local containers
+	_, local := executor.(containerExecutor)
+	if following || opts.Wait || transient || local {
+		// The logs already show what the APB is doing
+		result, err := executor.Wait(podName, ec.Location, !following)
+		printResult(action, bundleName, result, err)
+		if err != nil {
+			return podName, err
+		}
+		if opts.Finished != nil {
+			opts.Finished(result)
+		}
+		if action == "deprovision" {
+			err = deleteState(podName, ec.Location, id)
+		} else {
+			err = collectState(podName, ec.Location, id)
+		}
+		if err != nil {
+			log.Errorf("Failed to update the state of instance [%v]: %v", id, err)
+		}
+	}
+	err = nil
 
+	return
+}
+
+// newBundlePod returns the pod running an APB action
+func newBundlePod(ec runtime.ExecutionContext, customEnv []v1.EnvVar, opts RunOptions) (*v1.Pod, error) {
+	// Pod settings add labels, keep them off the sandbox
+	labels := map[string]string{}
+	for k, v := range ec.Metadata {
+		labels[k] = v
+	}
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   ec.BundleName,
-			Labels: ec.Metadata,
+			Labels: labels,
 		},
 		Spec: v1.PodSpec{
 			Containers: []v1.Container{
 				{
-					Name:  podName,
+					Name:  ec.BundleName,
 					Image: ec.Image,
 					Args: []string{
 						ec.Action,
-						"--extra-vars",
-						ec.ExtraVars,
 					},
-					Env:             createPodEnv(ec),
-					ImagePullPolicy: "Always",
+					Env:     createPodEnv(ec, customEnv),
+					EnvFrom: envFromSources(opts.EnvFromSecrets, opts.EnvFromConfigMaps),
 				},
 			},
 			RestartPolicy:      v1.RestartPolicyNever,
 			ServiceAccountName: ec.Account,
 		},
 	}
-	_, err = k8scli.Client.CoreV1().Pods(ns).Create(pod)
+	err := applyPodSettings(pod, opts.Pod)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	fmt.Printf("Successfully created pod [%v] to %s [%v] in namespace [%v]\n", podName, ec.Action, bundleName, ns)
-
-	if printLogs {
-		printBundleLogs(podName, ns, action)
+	if len(ec.Targets) > 1 {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[TargetNamespacesAnnotation] = strings.Join(ec.Targets[1:], ",")
+	}
+	if ec.StateName != "" {
+		addStateVolume(pod, ec.StateName, ec.StateLocation)
+	}
+	// The extra-vars hold parameter values, so they are passed through a secret rather than
+	// in the pod spec, where anyone who can read the pod would see them
+	if opts.InlineExtraVars {
+		pod.Spec.Containers[0].Args = append(pod.Spec.Containers[0].Args, "--extra-vars", ec.ExtraVars)
+	} else {
+		addExtraVarsVolume(pod, extraVarsSecretName(ec.BundleName))
 	}
+	return pod, nil
+}
 
-	return
+// printResult prints the outcome of an APB action
+func printResult(action string, bundleName string, result Result, err error) {
+	outcome := "succeeded"
+	if err != nil {
+		outcome = fmt.Sprintf("failed: %v", err)
+	}
+	fmt.Printf("APB %v of [%v] %v\n", action, bundleName, outcome)
+	if result.Description != "" {
+		fmt.Printf("Last operation: %v\n", result.Description)
+	}
+	if result.DashboardURL != "" {
+		fmt.Printf("Dashboard URL: %v\n", result.DashboardURL)
+	}
 }
 
 func GetPodStatus(namespace string, podName string) (string, error) {
@@ -188,47 +467,17 @@ func GetPodStatus(namespace string, podName string) (string, error) {
 	return string(status), nil
 }
 
-func printBundleLogs(podName string, namespace string, action string) {
-	k8scli, err := clients.Kubernetes()
-	if err != nil {
-		panic(err.Error())
-	}
-
-	logTailRequest := k8scli.Client.CoreV1().Pods(namespace).GetLogs(podName, &v1.PodLogOptions{Follow: true})
-
-	var requestStream io.ReadCloser
-	var podStarted bool
-
-	for podStarted == false {
-		requestStream, err = logTailRequest.Stream()
-		if err != nil {
-			fmt.Printf("Waiting for APB %v pod [%v] to start...\n", action, podName)
-			log.Debugf("%v", err)
-			time.Sleep(3 * time.Second)
-		} else {
-			fmt.Printf("Pod started. Reading logs...\n")
-			podStarted = true
-		}
-	}
-	defer requestStream.Close()
-
-	fmt.Println("-+- ---------------------- -+-")
-	fmt.Println(" |         APB LOGS         | ")
-	fmt.Println("-+- ---------------------- -+-")
-
-	buf := make([]byte, 100)
-	var doneReading bool
-	for doneReading == false {
-		n, err := requestStream.Read(buf)
-		if err == io.EOF {
-			doneReading = true
+// findPlan returns the plan of an APB with the given name
+func findPlan(spec *bundle.Spec, name string) (bundle.Plan, error) {
+	for _, plan := range spec.Plans {
+		if plan.Name == name {
+			return plan, nil
 		}
-		fmt.Printf("%s", buf[:n])
 	}
+	return bundle.Plan{}, fmt.Errorf("APB [%v] has no plan [%v]", spec.FQName, name)
 }
 
 func selectPlan(spec *bundle.Spec) bundle.Plan {
-	var planName string
 	var check = true
 	for check {
 		if len(spec.Plans) > 1 {
@@ -240,7 +489,11 @@ func selectPlan(spec *bundle.Spec) bundle.Plan {
 			return spec.Plans[0]
 		}
 		fmt.Printf("Enter name of plan to execute: ")
-		fmt.Scanln(&planName)
+		planName, err := readLine()
+		if err != nil {
+			log.Errorf("Error reading plan name: %v", err)
+			return bundle.Plan{}
+		}
 		for _, plan := range spec.Plans {
 			if plan.Name == planName {
 				return plan
@@ -251,19 +504,61 @@ func selectPlan(spec *bundle.Spec) bundle.Plan {
 	return bundle.Plan{}
 }
 
-func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
+// selectParameters collects the plan's parameters from the provided values and, if prompt is set, interactively
+// for the rest. It also returns which parameters are sensitive and must not be shown: password parameters,
+// references and the provided parameters named in sensitiveProvided.
+func selectParameters(plan bundle.Plan, provided map[string]string, sensitiveProvided []string, prompt bool) (bundle.Parameters, map[string]bool, error) {
 	schemaPlan, err := bundle.ConvertPlansToSchema([]bundle.Plan{plan})
 	if err != nil {
 		log.Errorf("Error converting APB plans to JSON Schema: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 	planSchema := schemaPlan[0].Schemas
 	schemaParams := planSchema.ServiceInstance.Create["parameters"]
 	params := bundle.Parameters{}
+	sensitive := map[string]bool{}
+	skipped := map[string]bool{}
+	currentGroup := ""
+	for name := range provided {
+		if !hasParameter(plan, name) {
+			return nil, nil, fmt.Errorf("plan [%v] has no parameter [%v]", plan.Name, name)
+		}
+	}
 	for _, param := range plan.Parameters {
 		var inputValid = false
 		var paramDefault interface{}
 
+		if param.DisplayType == "password" {
+			sensitive[param.Name] = true
+		}
+		if value, ok := provided[param.Name]; ok {
+			if isParamRef(value) || contains(sensitiveProvided, param.Name) {
+				sensitive[param.Name] = true
+			}
+			input, err := providedParamValue(value, param)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid value for parameter [%v]: %v", param.Name, err)
+			}
+			params.Add(param.Name, input)
+			continue
+		}
+		if !prompt {
+			continue
+		}
+		if !dependenciesMet(param, params) {
+			log.Debugf("Skipping parameter [%v], its dependencies are not met", param.Name)
+			skipped[param.Name] = true
+			continue
+		}
+		if param.DisplayGroup != currentGroup {
+			currentGroup = param.DisplayGroup
+			if currentGroup != "" {
+				fmt.Printf("\n== %v ==\n", currentGroup)
+			} else {
+				fmt.Println()
+			}
+		}
+
 		if param.Default != nil {
 			paramDefault = param.Default
 		}
@@ -285,8 +580,20 @@ func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
 					continue
 				}
 				paramInput = string(passwordInputBytes)
+			} else if isMultilineParam(param.Type, param.DisplayType) {
+				fmt.Printf("\n(finish with a line containing only '%v')\n", multilineTerminator)
+				paramInput, err = readMultiline()
+				if err != nil {
+					return nil, nil, err
+				}
 			} else {
-				fmt.Scanln(&paramInput)
+				paramInput, err = readLine()
+				if err == io.EOF {
+					return nil, nil, fmt.Errorf("input ended before parameter [%v] was entered", param.Name)
+				}
+				if err != nil {
+					return nil, nil, err
+				}
 			}
 
 			if paramInput == "" {
@@ -299,12 +606,25 @@ func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
 					paramInput = strconv.FormatFloat(paramDefault.(float64), 'f', 0, 32)
 				case bool:
 					paramInput = strconv.FormatBool(paramDefault.(bool))
+				case []interface{}, map[string]interface{}:
+					defaultInput, err := json.Marshal(paramDefault)
+					if err == nil {
+						paramInput = string(defaultInput)
+					}
 				}
 			}
 			if param.Required == true && paramInput == "" {
 				fmt.Printf("Parameter [%v] is required. Please try again.\n", param.Name)
 				continue
 			}
+			if paramInput == "" {
+				// Optional string parameters left empty are passed empty, as before. Other types have
+				// no empty value, so they are left out and the APB uses its own default.
+				if param.Type == "string" || param.Type == "" {
+					params.Add(param.Name, "")
+				}
+				break
+			}
 
 			if len(param.Enum) > 0 {
 				if !contains(param.Enum, paramInput) {
@@ -314,6 +634,9 @@ func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
 			}
 
 			input, err := pruneInput(paramInput, param)
+			if err == nil {
+				err = validateParamValue(input, param)
+			}
 			if err != nil {
 				fmt.Printf("Error accepting input: %v\n", err)
 				fmt.Println("Please try again")
@@ -323,17 +646,108 @@ func selectParameters(plan bundle.Plan) (bundle.Parameters, error) {
 			}
 		}
 	}
-	v := validator.New(schemaParams)
-	if err := v.Validate(params); err != nil {
-		log.Debugf("Error validating parameters: %v", err)
+	if prompt {
+		// Parameters skipped for unmet dependencies don't apply, even when required
+		required := []string{}
+		for _, name := range schemaParams.Required {
+			if !skipped[name] {
+				required = append(required, name)
+			}
+		}
+		schemaParams.Required = required
+		v := validator.New(schemaParams)
+		if err := v.Validate(params); err != nil {
+			log.Debugf("Error validating parameters: %v", err)
+			return nil, nil, err
+		}
+	}
+
+	log.Debugf("Params: %v\n", redactParams(params, sensitive))
+	return params, sensitive, nil
+}
+
+// providedParamValue resolves a parameter value given on the command line and converts it to the parameter's type
+func providedParamValue(value string, param bundle.ParameterDescriptor) (interface{}, error) {
+	value, err := resolveParamValue(value)
+	if err != nil {
 		return nil, err
 	}
+	if len(param.Enum) > 0 && !contains(param.Enum, value) {
+		return nil, fmt.Errorf("[%v] is not a valid option. Available options: %v", value, param.Enum)
+	}
+	input, err := pruneInput(value, param)
+	if err != nil {
+		return nil, err
+	}
+	return input, validateParamValue(input, param)
+}
+
+func hasParameter(plan bundle.Plan, name string) bool {
+	for _, param := range plan.Parameters {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func extraVarsSecretName(podName string) string {
+	return fmt.Sprintf("%v-extra-vars", podName)
+}
 
-	log.Debugf("Params: %v\n", params)
-	return params, nil
+// Mount the secret holding the extra-vars and pass it to the APB as an extra-vars file
+func addExtraVarsVolume(pod *v1.Pod, secretName string) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: "apb-extra-vars",
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{SecretName: secretName},
+		},
+	})
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      "apb-extra-vars",
+		MountPath: extraVarsMountPath,
+		ReadOnly:  true,
+	})
+	container.Args = append(container.Args, "--extra-vars", fmt.Sprintf("@%v/%v", extraVarsMountPath, extraVarsKey))
+}
+
+// Create the secret holding the extra-vars, owned by the pod so it is deleted along with it
+func createExtraVarsSecret(pod *v1.Pod, secretName string, extraVars []byte) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	_, err = k8scli.Client.CoreV1().Secrets(pod.Namespace).Create(extraVarsSecret(pod, secretName, extraVars))
+	if err != nil {
+		return fmt.Errorf("unable to create secret [%v] for extra-vars: %v", secretName, err)
+	}
+	return nil
+}
+
+// extraVarsSecret returns the secret holding the extra-vars of an APB pod, deleted along with it
+func extraVarsSecret(pod *v1.Pod, secretName string, extraVars []byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   secretName,
+			Labels: pod.Labels,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Pod",
+					Name:       pod.Name,
+					UID:        pod.UID,
+				},
+			},
+		},
+		Data: map[string][]byte{
+			extraVarsKey: extraVars,
+		},
+	}
 }
 
-func createPodEnv(executionContext runtime.ExecutionContext) []v1.EnvVar {
+// createPodEnv merges the proxy configuration and the custom variables into the pod environment
+func createPodEnv(executionContext runtime.ExecutionContext, customEnv []v1.EnvVar) []v1.EnvVar {
 	podEnv := []v1.EnvVar{
 		v1.EnvVar{
 			Name: "POD_NAME",
@@ -352,10 +766,17 @@ func createPodEnv(executionContext runtime.ExecutionContext) []v1.EnvVar {
 			},
 		},
 	}
-	return podEnv
+	if executionContext.StateName != "" {
+		podEnv = append(podEnv, v1.EnvVar{
+			Name:  stateLocationEnvVar,
+			Value: executionContext.StateLocation,
+		})
+	}
+	podEnv = append(podEnv, proxyEnv(executionContext.ProxyConfig)...)
+	return mergeEnv(podEnv, customEnv)
 }
 
-func createExtraVars(id string, targetNamespace string, parameters *bundle.Parameters, plan bundle.Plan) (string, error) {
+func createExtraVars(id string, targetNamespace string, targets []string, parameters *bundle.Parameters, plan bundle.Plan) (string, error) {
 	var paramsCopy bundle.Parameters
 	if parameters != nil && *parameters != nil {
 		paramsCopy = *parameters
@@ -366,6 +787,9 @@ func createExtraVars(id string, targetNamespace string, parameters *bundle.Param
 	if targetNamespace != "" {
 		paramsCopy["namespace"] = targetNamespace
 	}
+	if len(targets) > 1 {
+		paramsCopy[targetNamespacesVar] = targets
+	}
 
 	paramsCopy["cluster"] = "openshift"
 	paramsCopy["_apb_plan_id"] = plan.Name
@@ -398,6 +822,11 @@ func pruneInput(input string, param bundle.ParameterDescriptor) (interface{}, er
 		if err != nil {
 			return nil, errors.New("Input must be a float")
 		}
+	case "array", "object":
+		output, err = parseStructuredInput(input, param.Type)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		output = input
 	}
@@ -414,44 +843,39 @@ func contains(s []string, t string) bool {
 }
 
 func getProvisionedInstanceId(name, namespace string) (string, error) {
-	var instanceConfigs []config.ProvisionedInstance
-	err := config.ProvisionedInstances.UnmarshalKey("ProvisionedInstances", &instanceConfigs)
+	instances, err := ListInstances(namespace, fmt.Sprintf("%v=%v", BundleFQNameLabel, name))
 	if err != nil {
 		return "", err
 	}
-	for _, instance := range instanceConfigs {
-		if instance.BundleName == name {
-			if len(instance.InstanceIDs[namespace]) == 1 {
-				return instance.InstanceIDs[namespace][0], nil
-			} else if len(instance.InstanceIDs[namespace]) == 0 {
-				return "", fmt.Errorf("found no available instances in namespace [%v]", namespace)
-			} else {
-				// Select instance
-				fmt.Printf("Found more than one service instance for bundle [%v]:\n", name)
-				for i, instance := range instance.InstanceIDs[namespace] {
-					fmt.Printf("[%v] - %v\n", i, instance)
-				}
-				var inputValid = false
-				for !inputValid {
-					var input string
-					fmt.Printf("Enter the number of the instance ID you would wish to deprovision: ")
-					fmt.Scanln(&input)
-					if input == "" {
-						continue
-					}
-					intInput, err := strconv.Atoi(input)
-					if err != nil {
-						fmt.Printf("Input was not a valid integer, please enter again.\n")
-						continue
-					}
-					if intInput >= len(instance.InstanceIDs[namespace]) || intInput < 0 {
-						fmt.Printf("Input is out of range. Please select an integer from 0-%v\n", len(instance.InstanceIDs[namespace])-1)
-						continue
-					}
-					return instance.InstanceIDs[namespace][intInput], nil
-				}
-			}
+	if len(instances) == 0 {
+		return "", fmt.Errorf("No provisioned instances for bundle [%v] in namespace [%v]", name, namespace)
+	}
+	if len(instances) == 1 {
+		return instances[0].ID, nil
+	}
+	// Select instance
+	fmt.Printf("Found more than one service instance for bundle [%v]:\n", name)
+	for i, instance := range instances {
+		fmt.Printf("[%v] - %v\n", i, instance.ID)
+	}
+	for {
+		fmt.Printf("Enter the number of the instance ID you would wish to deprovision: ")
+		input, err := readLine()
+		if err != nil {
+			return "", err
+		}
+		if input == "" {
+			continue
+		}
+		intInput, err := strconv.Atoi(input)
+		if err != nil {
+			fmt.Printf("Input was not a valid integer, please enter again.\n")
+			continue
+		}
+		if intInput >= len(instances) || intInput < 0 {
+			fmt.Printf("Input is out of range. Please select an integer from 0-%v\n", len(instances)-1)
+			continue
 		}
+		return instances[intInput].ID, nil
 	}
-	return "", fmt.Errorf("No provisioned instances for bundle [%v] in namespace [%v]", name, namespace)
 }
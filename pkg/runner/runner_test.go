@@ -1,8 +1,11 @@
 package runner
 
 import (
-	"github.com/automationbroker/bundle-lib/bundle"
+	"strings"
 	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"k8s.io/api/core/v1"
 )
 
 func TestContains(t *testing.T) {
@@ -133,6 +136,38 @@ func TestPruneInput(t *testing.T) {
 			input:     "22.4",
 			shouldErr: false,
 		},
+		{
+			name: "test valid JSON array",
+			param: bundle.ParameterDescriptor{
+				Type: "array",
+			},
+			input:     `["a", "b"]`,
+			shouldErr: false,
+		},
+		{
+			name: "test valid YAML object",
+			param: bundle.ParameterDescriptor{
+				Type: "object",
+			},
+			input:     "size: 1Gi\nlabels:\n  app: db",
+			shouldErr: false,
+		},
+		{
+			name: "test invalid array",
+			param: bundle.ParameterDescriptor{
+				Type: "array",
+			},
+			input:     `{"a": 1}`,
+			shouldErr: true,
+		},
+		{
+			name: "test invalid object",
+			param: bundle.ParameterDescriptor{
+				Type: "object",
+			},
+			input:     "- a\n- b",
+			shouldErr: true,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -167,7 +202,38 @@ func TestPruneInput(t *testing.T) {
 					t.Fatalf("got unexpected output type [%v]. expected [bool]", tc.param.Type)
 					return
 				}
+			case []interface{}:
+				if tc.param.Type != "array" {
+					t.Fatalf("got unexpected output type [%v]. expected [array]", tc.param.Type)
+					return
+				}
+			case map[string]interface{}:
+				if tc.param.Type != "object" {
+					t.Fatalf("got unexpected output type [%v]. expected [object]", tc.param.Type)
+					return
+				}
 			}
 		})
 	}
 }
+
+func TestAddExtraVarsVolume(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "apb", Args: []string{"provision"}}},
+		},
+	}
+	addExtraVarsVolume(pod, "bundle-provision-1234-extra-vars")
+
+	if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].Secret.SecretName != "bundle-provision-1234-extra-vars" {
+		t.Fatalf("expected extra-vars secret volume, got %v", pod.Spec.Volumes)
+	}
+	container := pod.Spec.Containers[0]
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].MountPath != extraVarsMountPath {
+		t.Fatalf("expected extra-vars volume mounted at [%v], got %v", extraVarsMountPath, container.VolumeMounts)
+	}
+	expectedArgs := []string{"provision", "--extra-vars", "@/etc/apb-extra-vars/extra-vars.json"}
+	if strings.Join(container.Args, " ") != strings.Join(expectedArgs, " ") {
+		t.Fatalf("expected args %v, got %v", expectedArgs, container.Args)
+	}
+}
@@ -0,0 +1,115 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AutoSandboxNamespace names the transient namespace after the APB and action, like the broker does
+const AutoSandboxNamespace = "auto"
+
+// sandboxNamespacePrefix returns the prefix of the generated name of a transient namespace
+func sandboxNamespacePrefix(sandboxNamespace string, fqName string, action string) string {
+	if sandboxNamespace == AutoSandboxNamespace {
+		return fmt.Sprintf("%s-%.4s-", fqName, action)
+	}
+	if !strings.HasSuffix(sandboxNamespace, "-") {
+		return sandboxNamespace + "-"
+	}
+	return sandboxNamespace
+}
+
+// copyEnvSources copies the secrets and configmaps the APB pod reads its environment from into
+// the transient namespace
+func copyEnvSources(ec runtime.ExecutionContext, from string, secrets []string, configMaps []string) error {
+	if len(secrets) > 0 {
+		err := runtime.Provider.CopySecretsToNamespace(ec, from, secrets)
+		if err != nil {
+			return fmt.Errorf("unable to copy secrets %v to namespace [%v]: %v", secrets, ec.Location, err)
+		}
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	for _, name := range configMaps {
+		cm, err := k8scli.Client.CoreV1().ConfigMaps(from).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to read configmap [%v]: %v", name, err)
+		}
+		_, err = k8scli.Client.CoreV1().ConfigMaps(ec.Location).Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: cm.Name, Labels: cm.Labels},
+			Data:       cm.Data,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to copy configmap [%v] to namespace [%v]: %v", name, ec.Location, err)
+		}
+	}
+	return nil
+}
+
+// destroySandbox removes a transient namespace once the APB pod finished. The credentials the APB
+// extracted are copied to the target namespace first, so they can still be bound. The namespace is
+// kept when they can't be copied.
+func destroySandbox(ec runtime.ExecutionContext, namespace string, keepOnError bool) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		log.Errorf("Unable to clean up transient namespace [%v]: %v", ec.Location, err)
+		return
+	}
+	keep := false
+	_, err = k8scli.Client.CoreV1().Secrets(ec.Location).Get(ec.BundleName, metav1.GetOptions{})
+	if err == nil {
+		target := ec
+		target.Location = namespace
+		err = runtime.Provider.CopySecretsToNamespace(target, ec.Location, []string{ec.BundleName})
+		if err != nil {
+			log.Errorf("Unable to copy credentials [%v] to namespace [%v]: %v", ec.BundleName, namespace, err)
+			fmt.Printf("Keeping transient namespace [%v] with the credentials of the APB\n", ec.Location)
+			keep = true
+		}
+	} else if !errors.IsNotFound(err) {
+		log.Errorf("Unable to read credentials [%v]: %v", ec.BundleName, err)
+	}
+
+	if !keep {
+		pod, podErr := k8scli.Client.CoreV1().Pods(ec.Location).Get(ec.BundleName, metav1.GetOptions{})
+		if keepOnError && !podSucceeded(pod, podErr) {
+			fmt.Printf("Keeping transient namespace [%v] of the failed APB\n", ec.Location)
+			keep = true
+		} else {
+			fmt.Printf("Deleting transient namespace [%v]\n", ec.Location)
+		}
+	}
+	runtime.Provider.DestroySandbox(ec.BundleName, ec.Location, ec.Targets, namespace, keep, false)
+}
+
+// podSucceeded tells whether an APB pod completed successfully. Pods that never ran, like ones
+// stuck pulling their image, did not.
+func podSucceeded(pod *v1.Pod, err error) bool {
+	return err == nil && pod != nil && pod.Status.Phase == v1.PodSucceeded
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestSandboxNamespacePrefix(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name             string
+		sandboxNamespace string
+		expected         string
+	}{
+		{
+			name:             "test auto",
+			sandboxNamespace: AutoSandboxNamespace,
+			expected:         "postgresql-apb-prov-",
+		},
+		{
+			name:             "test name",
+			sandboxNamespace: "apb-test",
+			expected:         "apb-test-",
+		},
+		{
+			name:             "test name ending with dash",
+			sandboxNamespace: "apb-test-",
+			expected:         "apb-test-",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix := sandboxNamespacePrefix(tc.sandboxNamespace, "postgresql-apb", "provision")
+			if prefix != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, prefix)
+			}
+		})
+	}
+}
+
+func TestPodSucceeded(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name     string
+		pod      *v1.Pod
+		err      error
+		expected bool
+	}{
+		{
+			name:     "test succeeded",
+			pod:      &v1.Pod{Status: v1.PodStatus{Phase: v1.PodSucceeded}},
+			expected: true,
+		},
+		{
+			name: "test failed",
+			pod:  &v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed}},
+		},
+		{
+			name: "test pending",
+			pod:  &v1.Pod{Status: v1.PodStatus{Phase: v1.PodPending}},
+		},
+		{
+			name: "test unreadable",
+			pod:  &v1.Pod{},
+			err:  fmt.Errorf("pods \"bundle-1\" not found"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			succeeded := podSucceeded(tc.pod, tc.err)
+			if succeeded != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, succeeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// stateVolume is the volume of the APB pod holding the state of its instance
+const stateVolume = "apb-state"
+
+// APBs save their state in a configmap named after their pod. Between actions, the state of an
+// instance is kept in the master state namespace, in a configmap named after the instance, the
+// same way the broker does.
+
+// stateNamespace returns the master state namespace, defaulting to the namespace of the instance
+func stateNamespace(configured string, namespace string) string {
+	if configured != "" {
+		return configured
+	}
+	return namespace
+}
+
+// providerLock guards runtime.Provider, which WaitForBundle reads from several goroutines while
+// RunBundle configures it for another APB
+var providerLock sync.Mutex
+
+// newStateRuntime configures the runtime to keep state in the master state namespace
+func newStateRuntime(masterNamespace string) {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+	if runtime.Provider != nil && runtime.Provider.MasterNamespace() == masterNamespace {
+		return
+	}
+	runtime.NewRuntime(runtime.Configuration{StateMasterNamespace: masterNamespace})
+}
+
+// currentProvider returns the runtime, configuring a default one if there is none yet
+func currentProvider() runtime.Runtime {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+	if runtime.Provider == nil {
+		runtime.NewRuntime(runtime.Configuration{})
+	}
+	return runtime.Provider
+}
+
+// configMapExists tells whether a configmap exists
+func configMapExists(namespace string, name string) (bool, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return false, err
+	}
+	_, err = k8scli.Client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// collectState copies the state an APB pod saved into the master state of its instance, and removes
+// the pod's copy
+func collectState(podName string, podNamespace string, id string) error {
+	present, err := configMapExists(podNamespace, podName)
+	if err != nil || !present {
+		return err
+	}
+	masterName := runtime.Provider.MasterName(id)
+	log.Debugf("Copying state of pod [%v] to [%v] in namespace [%v]", podName, masterName, runtime.Provider.MasterNamespace())
+	err = runtime.Provider.CopyState(podName, masterName, podNamespace, runtime.Provider.MasterNamespace())
+	if err != nil {
+		return fmt.Errorf("unable to save the state of pod [%v]: %v", podName, err)
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	err = k8scli.Client.CoreV1().ConfigMaps(podNamespace).Delete(podName, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		log.Warningf("Unable to remove state configmap [%v] of pod [%v]: %v", podName, podName, err)
+	}
+	return nil
+}
+
+// collectProvisionState saves state left by a provision pod that apb didn't wait for
+func collectProvisionState(namespace string, id string) error {
+	return collectState(fmt.Sprintf("bundle-provision-%v", id), namespace, id)
+}
+
+// deleteState removes the master state of a deprovisioned instance, then the copy made for its
+// deprovision pod. The copy is removed last, so one left behind marks a cleanup still to do.
+func deleteState(podName string, podNamespace string, id string) error {
+	err := runtime.Provider.DeleteState(runtime.Provider.MasterName(id))
+	if err != nil {
+		return fmt.Errorf("unable to delete the state of instance [%v]: %v", id, err)
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	err = k8scli.Client.CoreV1().ConfigMaps(podNamespace).Delete(podName, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("unable to remove state configmap [%v] of pod [%v]: %v", podName, podName, err)
+	}
+	return nil
+}
+
+// collectDeprovisionedState deletes the state left by deprovision pods that apb didn't wait for
+func collectDeprovisionedState(namespace string) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	pods, err := k8scli.Client.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: BundleActionLabel + "=deprovision"})
+	if err != nil {
+		return fmt.Errorf("unable to list APB pods in namespace [%v]: %v", namespace, err)
+	}
+	for _, pod := range deprovisionedPods(pods.Items) {
+		present, err := configMapExists(namespace, pod.Name)
+		if err != nil {
+			return err
+		}
+		if !present {
+			continue
+		}
+		log.Debugf("Deleting state of instance [%v] deprovisioned by pod [%v]", podInstanceID(pod), pod.Name)
+		err = deleteState(pod.Name, namespace, podInstanceID(pod))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deprovisionedPods returns the deprovision pods that succeeded
+func deprovisionedPods(pods []v1.Pod) []v1.Pod {
+	succeeded := []v1.Pod{}
+	for _, pod := range pods {
+		if pod.Labels[BundleActionLabel] == "deprovision" && pod.Status.Phase == v1.PodSucceeded && podInstanceID(pod) != "" {
+			succeeded = append(succeeded, pod)
+		}
+	}
+	return succeeded
+}
+
+// prepareState copies the master state of an instance next to the APB pod, and returns the name
+// of the copy, or an empty name when the instance has no state
+func prepareState(ec runtime.ExecutionContext, id string) (string, error) {
+	masterName := runtime.Provider.MasterName(id)
+	present, err := configMapExists(runtime.Provider.MasterNamespace(), masterName)
+	if err != nil || !present {
+		return "", err
+	}
+	err = runtime.Provider.CopyState(masterName, ec.BundleName, runtime.Provider.MasterNamespace(), ec.Location)
+	if err != nil {
+		return "", fmt.Errorf("unable to copy state of instance [%v]: %v", id, err)
+	}
+	return ec.BundleName, nil
+}
+
+// addStateVolume mounts the state configmap into the APB pod, where apb-base reads it from
+func addStateVolume(pod *v1.Pod, configMapName string, mountPath string) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: stateVolume,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: configMapName},
+			},
+		},
+	})
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      stateVolume,
+		MountPath: mountPath,
+		ReadOnly:  true,
+	})
+}
+
+// InstanceState returns the state saved by the APBs of an instance, or nil if there is none. It only
+// reads the state: the state of a provision apb didn't wait for is read from the pod's copy.
+func InstanceState(namespace string, configuredStateNamespace string, id string) (map[string]string, error) {
+	newStateRuntime(stateNamespace(configuredStateNamespace, namespace))
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	locations := []struct{ namespace, name string }{
+		{runtime.Provider.MasterNamespace(), runtime.Provider.MasterName(id)},
+		{namespace, fmt.Sprintf("bundle-provision-%v", id)},
+	}
+	for _, l := range locations {
+		cm, err := k8scli.Client.CoreV1().ConfigMaps(l.namespace).Get(l.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cm.Data, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStateNamespace(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name       string
+		configured string
+		expected   string
+	}{
+		{
+			name:       "defaults to the instance namespace",
+			configured: "",
+			expected:   "myproject",
+		},
+		{
+			name:       "configured namespace",
+			configured: "apb-state",
+			expected:   "apb-state",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ns := stateNamespace(tc.configured, "myproject")
+			if ns != tc.expected {
+				t.Fatalf("expected [%v], got [%v]", tc.expected, ns)
+			}
+		})
+	}
+}
+
+func TestAddStateVolume(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "apb"}},
+		},
+	}
+	addStateVolume(pod, "bundle-deprovision-1234", "/etc/apb/state")
+
+	if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].ConfigMap.Name != "bundle-deprovision-1234" {
+		t.Fatalf("expected state configmap volume, got %v", pod.Spec.Volumes)
+	}
+	container := pod.Spec.Containers[0]
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].MountPath != "/etc/apb/state" {
+		t.Fatalf("expected state volume mounted at [/etc/apb/state], got %v", container.VolumeMounts)
+	}
+}
+
+func TestCreatePodEnvState(t *testing.T) {
+	ec := runtime.ExecutionContext{StateName: "bundle-deprovision-1234", StateLocation: "/etc/apb/state"}
+	env := createPodEnv(ec, nil)
+	for _, e := range env {
+		if e.Name == "BUNDLE_STATE_LOCATION" {
+			if e.Value != "/etc/apb/state" {
+				t.Fatalf("expected state location [/etc/apb/state], got [%v]", e.Value)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected BUNDLE_STATE_LOCATION in %v", env)
+}
+
+func TestDeprovisionedPods(t *testing.T) {
+	pod := func(name string, action string, phase v1.PodPhase) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{BundleActionLabel: action}},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+	pods := []v1.Pod{
+		pod("bundle-deprovision-1234", "deprovision", v1.PodSucceeded),
+		pod("bundle-deprovision-5678", "deprovision", v1.PodRunning),
+		pod("bundle-deprovision-9abc", "deprovision", v1.PodFailed),
+		pod("bundle-provision-1234", "provision", v1.PodSucceeded),
+		pod("custom", "deprovision", v1.PodSucceeded),
+	}
+	succeeded := deprovisionedPods(pods)
+	if len(succeeded) != 1 || succeeded[0].Name != "bundle-deprovision-1234" {
+		t.Fatalf("expected [bundle-deprovision-1234], got %v", succeeded)
+	}
+}
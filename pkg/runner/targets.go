@@ -0,0 +1,83 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/util"
+)
+
+// TargetNamespacesAnnotation lists the extra target namespaces of an APB pod, comma separated
+const TargetNamespacesAnnotation = "apb.automationbroker.io/target-namespaces"
+
+// targetNamespacesVar is the extra-var listing every namespace the APB may act on
+const targetNamespacesVar = "target_namespaces"
+
+// targetNamespaces returns the namespace followed by the extra targets, without duplicates
+func targetNamespaces(namespace string, extra []string) []string {
+	targets := []string{namespace}
+	for _, t := range extra {
+		if t != "" && !contains(targets, t) {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// parseTargetNamespaces reads the value of TargetNamespacesAnnotation
+func parseTargetNamespaces(annotation string) []string {
+	if annotation == "" {
+		return nil
+	}
+	return strings.Split(annotation, ",")
+}
+
+// recordedTargets returns the extra target namespaces an instance was provisioned with
+func recordedTargets(namespace string, id string) ([]string, error) {
+	instances, err := ListInstances(namespace, fmt.Sprintf("%v=%v", BundleInstanceIDLabel, id))
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if instance.ID == id {
+			return instance.TargetNamespaces, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkTargetAccess makes sure the current user may grant the sandbox role in the extra targets,
+// before the sandbox is created
+func checkTargetAccess(targets []string) error {
+	for _, t := range targets[1:] {
+		allowed, err := util.CanI(util.ResourceAccess{
+			Namespace: t,
+			Verb:      "create",
+			Group:     "rbac.authorization.k8s.io",
+			Resource:  "rolebindings",
+		})
+		if err != nil {
+			return fmt.Errorf("unable to check access to target namespace [%v]: %v", t, err)
+		}
+		if !allowed {
+			return fmt.Errorf("not allowed to create role bindings in target namespace [%v]", t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/apb/pkg/config"
+	"github.com/automationbroker/bundle-lib/bundle"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestTargetNamespaces(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name     string
+		extra    []string
+		expected []string
+	}{
+		{
+			name:     "test no extra targets",
+			expected: []string{"apb"},
+		},
+		{
+			name:     "test duplicates removed",
+			extra:    []string{"db", "apb", "db", "", "cache"},
+			expected: []string{"apb", "db", "cache"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			targets := targetNamespaces("apb", tc.extra)
+			if !reflect.DeepEqual(targets, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, targets)
+			}
+		})
+	}
+}
+
+func TestCreateExtraVarsTargets(t *testing.T) {
+	params := bundle.Parameters{}
+	extraVars, err := createExtraVars("1234", "apb", []string{"apb", "db"}, &params, bundle.Plan{Name: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars := map[string]interface{}{}
+	err = json.Unmarshal([]byte(extraVars), &vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(vars[targetNamespacesVar], []interface{}{"apb", "db"}) {
+		t.Fatalf("expected target namespaces in extra-vars, got %v", vars)
+	}
+
+	params = bundle.Parameters{}
+	extraVars, err = createExtraVars("1234", "apb", []string{"apb"}, &params, bundle.Plan{Name: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars = map[string]interface{}{}
+	json.Unmarshal([]byte(extraVars), &vars)
+	if _, ok := vars[targetNamespacesVar]; ok {
+		t.Fatalf("expected no target namespaces for a single target, got %v", vars)
+	}
+}
+
+func TestMergeInstancesTargets(t *testing.T) {
+	local := []config.ProvisionedInstance{
+		{
+			BundleName:       "postgresql-apb",
+			InstanceIDs:      map[string][]string{"apb": {"1111", "2222"}},
+			TargetNamespaces: map[string][]string{"2222": {"db"}},
+		},
+	}
+	pod := bundlePod("bundle-provision-1111", map[string]string{BundleFQNameLabel: "postgresql-apb", BundleActionLabel: "provision"}, v1.PodSucceeded)
+	pod.Annotations = map[string]string{TargetNamespacesAnnotation: "db,cache"}
+	instances := mergeInstances(local, []v1.Pod{pod}, "apb", labels.Everything())
+	targets := map[string][]string{}
+	for _, instance := range instances {
+		targets[instance.ID] = instance.TargetNamespaces
+	}
+	expected := map[string][]string{"1111": {"db", "cache"}, "2222": {"db"}}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Fatalf("expected %v, got %v", expected, targets)
+	}
+}
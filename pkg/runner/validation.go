@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runner
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// dependenciesMet returns true if every dependency of a parameter holds for the parameters collected so far.
+// A dependency without a value only requires the other parameter to be set.
+func dependenciesMet(param bundle.ParameterDescriptor, params bundle.Parameters) bool {
+	for _, dep := range param.Dependencies {
+		value, ok := params[dep.Key]
+		if !ok {
+			return false
+		}
+		if dep.Value != nil && fmt.Sprint(value) != fmt.Sprint(dep.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateParamValue checks a value against the validators of its parameter, so mistakes are reported
+// at the input that caused them
+func validateParamValue(value interface{}, param bundle.ParameterDescriptor) error {
+	switch v := value.(type) {
+	case string:
+		return validateString(v, param)
+	case int64:
+		return validateNumber(float64(v), param)
+	case float64:
+		return validateNumber(v, param)
+	}
+	return nil
+}
+
+func validateString(value string, param bundle.ParameterDescriptor) error {
+	length := utf8.RuneCountInString(value)
+	maxLength := param.MaxLength
+	if maxLength == 0 {
+		maxLength = param.DeprecatedMaxlength
+	}
+	if param.MinLength > 0 && length < param.MinLength {
+		return fmt.Errorf("must be at least %v characters long", param.MinLength)
+	}
+	if maxLength > 0 && length > maxLength {
+		return fmt.Errorf("must be at most %v characters long", maxLength)
+	}
+	if param.Pattern != "" {
+		re, err := regexp.Compile(param.Pattern)
+		if err != nil {
+			return fmt.Errorf("APB declares an invalid pattern [%v]: %v", param.Pattern, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("must match pattern [%v]", param.Pattern)
+		}
+	}
+	return nil
+}
+
+func validateNumber(value float64, param bundle.ParameterDescriptor) error {
+	if param.Minimum != nil && value < float64(*param.Minimum) {
+		return fmt.Errorf("must be greater than or equal to %v", float64(*param.Minimum))
+	}
+	if param.ExclusiveMinimum != nil && value <= float64(*param.ExclusiveMinimum) {
+		return fmt.Errorf("must be greater than %v", float64(*param.ExclusiveMinimum))
+	}
+	if param.Maximum != nil && value > float64(*param.Maximum) {
+		return fmt.Errorf("must be less than or equal to %v", float64(*param.Maximum))
+	}
+	if param.ExclusiveMaximum != nil && value >= float64(*param.ExclusiveMaximum) {
+		return fmt.Errorf("must be less than %v", float64(*param.ExclusiveMaximum))
+	}
+	if param.MultipleOf > 0 {
+		quotient := value / param.MultipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			return fmt.Errorf("must be a multiple of %v", param.MultipleOf)
+		}
+	}
+	return nil
+}
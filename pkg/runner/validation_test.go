@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+func nilableNumber(n float64) *bundle.NilableNumber {
+	v := bundle.NilableNumber(n)
+	return &v
+}
+
+func TestDependenciesMet(t *testing.T) {
+	params := bundle.Parameters{"backup": true, "storage": "nfs"}
+	// test case table
+	testCases := []struct {
+		name string
+		deps []bundle.Dependency
+		met  bool
+	}{
+		{
+			name: "test no dependencies",
+			met:  true,
+		},
+		{
+			name: "test matching values",
+			deps: []bundle.Dependency{{Key: "backup", Value: true}, {Key: "storage", Value: "nfs"}},
+			met:  true,
+		},
+		{
+			name: "test different value",
+			deps: []bundle.Dependency{{Key: "storage", Value: "ceph"}},
+			met:  false,
+		},
+		{
+			name: "test key only",
+			deps: []bundle.Dependency{{Key: "storage"}},
+			met:  true,
+		},
+		{
+			name: "test missing key",
+			deps: []bundle.Dependency{{Key: "replicas"}},
+			met:  false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			param := bundle.ParameterDescriptor{Name: "param", Dependencies: tc.deps}
+			if dependenciesMet(param, params) != tc.met {
+				t.Fatalf("expected dependenciesMet to be [%v]", tc.met)
+			}
+		})
+	}
+}
+
+func TestValidateParamValue(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name      string
+		param     bundle.ParameterDescriptor
+		value     interface{}
+		shouldErr bool
+	}{
+		{
+			name:  "test string within length",
+			param: bundle.ParameterDescriptor{MinLength: 2, MaxLength: 4},
+			value: "abc",
+		},
+		{
+			name:      "test string too short",
+			param:     bundle.ParameterDescriptor{MinLength: 4},
+			value:     "abc",
+			shouldErr: true,
+		},
+		{
+			name:      "test string too long with deprecated maxlength",
+			param:     bundle.ParameterDescriptor{DeprecatedMaxlength: 2},
+			value:     "abc",
+			shouldErr: true,
+		},
+		{
+			name:  "test string matching pattern",
+			param: bundle.ParameterDescriptor{Pattern: "^[a-z]+$"},
+			value: "abc",
+		},
+		{
+			name:      "test string not matching pattern",
+			param:     bundle.ParameterDescriptor{Pattern: "^[a-z]+$"},
+			value:     "ABC",
+			shouldErr: true,
+		},
+		{
+			name:  "test integer in range",
+			param: bundle.ParameterDescriptor{Minimum: nilableNumber(1), Maximum: nilableNumber(10)},
+			value: int64(10),
+		},
+		{
+			name:      "test integer below minimum",
+			param:     bundle.ParameterDescriptor{Minimum: nilableNumber(1)},
+			value:     int64(0),
+			shouldErr: true,
+		},
+		{
+			name:      "test number at exclusive maximum",
+			param:     bundle.ParameterDescriptor{ExclusiveMaximum: nilableNumber(1.5)},
+			value:     1.5,
+			shouldErr: true,
+		},
+		{
+			name:  "test multiple of",
+			param: bundle.ParameterDescriptor{MultipleOf: 0.5},
+			value: 2.5,
+		},
+		{
+			name:      "test not multiple of",
+			param:     bundle.ParameterDescriptor{MultipleOf: 3},
+			value:     int64(10),
+			shouldErr: true,
+		},
+		{
+			name:  "test boolean ignored",
+			param: bundle.ParameterDescriptor{MinLength: 10},
+			value: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateParamValue(tc.value, tc.param)
+			if tc.shouldErr && err == nil {
+				t.Fatalf("expected validation of [%v] to fail", tc.value)
+			}
+			if !tc.shouldErr && err != nil {
+				t.Fatalf("unexpected validation error for [%v]: %v", tc.value, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package servicecatalog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/automationbroker/apb/pkg/util"
+	log "github.com/sirupsen/logrus"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// BrokerScopeCluster selects a cluster-scoped ClusterServiceBroker
+	BrokerScopeCluster = "cluster"
+	// BrokerScopeNamespace selects a namespaced ServiceBroker
+	BrokerScopeNamespace = "namespace"
+)
+
+const relistPollInterval = 2 * time.Second
+
+// NamespacedBrokerPath returns the API path of the namespaced ServiceBroker name in namespace
+func NamespacedBrokerPath(namespace string, name string) string {
+	return fmt.Sprintf("%v/%v", namespacedPath(namespace, "servicebrokers"), name)
+}
+
+// DetectBrokerScope reports whether a broker exists at clusterPath or namespacedPath. It is an error
+// for the broker to exist at both or neither.
+func DetectBrokerScope(clusterPath string, namespacedPath string) (string, error) {
+	_, clusterErr := GetBroker(clusterPath)
+	if clusterErr != nil && !kapierrors.IsNotFound(clusterErr) {
+		return "", clusterErr
+	}
+	_, namespacedErr := GetBroker(namespacedPath)
+	if namespacedErr != nil && !kapierrors.IsNotFound(namespacedErr) && !kapierrors.IsForbidden(namespacedErr) {
+		return "", namespacedErr
+	}
+	switch {
+	case clusterErr == nil && namespacedErr == nil:
+		return "", fmt.Errorf("found brokers at both [%v] and [%v]", clusterPath, namespacedPath)
+	case clusterErr == nil:
+		return BrokerScopeCluster, nil
+	case namespacedErr == nil:
+		return BrokerScopeNamespace, nil
+	}
+	return "", clusterErr
+}
+
+// GetBroker returns the broker at resourcePath
+func GetBroker(resourcePath string) (*ServiceBroker, error) {
+	broker := &ServiceBroker{}
+	err := getResource(resourcePath, broker)
+	if err != nil {
+		return nil, err
+	}
+	return broker, nil
+}
+
+// RelistBroker increments spec.relistRequests on the broker at resourcePath. The update carries the
+// resourceVersion that was read, so a concurrent relist causes a conflict and the increment is retried.
+func RelistBroker(resourcePath string) (int64, error) {
+	var relistRequests int64
+	_, err := util.UpdateResource(resourcePath, func(broker *unstructured.Unstructured) error {
+		relistRequests, _ = unstructured.NestedInt64(broker.Object, "spec", "relistRequests")
+		relistRequests++
+		unstructured.SetNestedField(broker.Object, relistRequests, "spec", "relistRequests")
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return relistRequests, nil
+}
+
+// WaitForBrokerRelist polls the broker at resourcePath until it has retrieved its catalog after
+// previousRetrieval and reports Ready. Catalog errors reported in the broker conditions are returned.
+func WaitForBrokerRelist(resourcePath string, previousRetrieval *metav1.Time, timeout time.Duration) (*ServiceBroker, error) {
+	var broker *ServiceBroker
+	var catalogErr error
+	err := wait.PollImmediate(relistPollInterval, timeout, func() (bool, error) {
+		var err error
+		broker, err = GetBroker(resourcePath)
+		if err != nil {
+			return false, err
+		}
+		ready := GetCondition(broker.Status.Conditions, "Ready")
+		if ready != nil && ready.Status == "False" && strings.HasPrefix(ready.Reason, "Error") && isAfter(&ready.LastTransitionTime, previousRetrieval) {
+			catalogErr = fmt.Errorf("%v: %v", ready.Reason, ready.Message)
+			return true, nil
+		}
+		if !isAfter(broker.Status.LastCatalogRetrievalTime, previousRetrieval) {
+			log.Debugf("Waiting for broker [%v] to retrieve its catalog", broker.Name)
+			return false, nil
+		}
+		return ready != nil && ready.Status == "True", nil
+	})
+	if catalogErr != nil {
+		return broker, catalogErr
+	}
+	if err == wait.ErrWaitTimeout && broker != nil {
+		if ready := GetCondition(broker.Status.Conditions, "Ready"); ready != nil && ready.Status != "True" {
+			return broker, fmt.Errorf("timed out waiting for relist, broker is not ready: %v: %v", ready.Reason, ready.Message)
+		}
+		return broker, fmt.Errorf("timed out waiting for broker to retrieve its catalog")
+	}
+	return broker, err
+}
+
+// Whether t is set and later than previous. Any set time is later than an unset previous time.
+func isAfter(t *metav1.Time, previous *metav1.Time) bool {
+	if t == nil || t.IsZero() {
+		return false
+	}
+	if previous == nil {
+		return true
+	}
+	return t.Time.After(previous.Time)
+}
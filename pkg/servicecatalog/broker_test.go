@@ -0,0 +1,53 @@
+package servicecatalog
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsAfter(t *testing.T) {
+	now := metav1.NewTime(time.Now())
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	// test case table
+	testCases := []struct {
+		name     string
+		t        *metav1.Time
+		previous *metav1.Time
+		isAfter  bool
+	}{
+		{
+			name:     "test later time",
+			t:        &now,
+			previous: &earlier,
+			isAfter:  true,
+		},
+		{
+			name:     "test unchanged time",
+			t:        &earlier,
+			previous: &earlier,
+			isAfter:  false,
+		},
+		{
+			name:     "test unset previous time",
+			t:        &now,
+			previous: nil,
+			isAfter:  true,
+		},
+		{
+			name:     "test unset time",
+			t:        nil,
+			previous: &earlier,
+			isAfter:  false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if isAfter(tc.t, tc.previous) != tc.isAfter {
+				t.Fatalf("expected isAfter [%v], got [%v]", tc.isAfter, !tc.isAfter)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package servicecatalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// DiffEntry describes one APB as seen by the registry cache, the broker catalog and the Service Catalog
+type DiffEntry struct {
+	Name             string
+	ID               string
+	InCache          bool
+	InBroker         bool
+	InServiceCatalog bool
+	Problems         []string
+}
+
+// DiffCatalogs joins the cached specs, the broker catalog and the broker's ClusterServiceClasses and plans.
+// Broker services and classes are joined on service ID. Cached specs are joined to broker services by
+// name, allowing for the registry prefix the broker adds to each FQName.
+func DiffCatalogs(specs []*bundle.Spec, services []osb.Service, classes []ClusterServiceClass, plans []ClusterServicePlan) []DiffEntry {
+	entries := []DiffEntry{}
+	matchedSpecs := map[*bundle.Spec]bool{}
+	matchedClasses := map[string]bool{}
+
+	classesByID := map[string]ClusterServiceClass{}
+	for _, c := range classes {
+		classesByID[c.Spec.ExternalID] = c
+	}
+	plansByClass := map[string][]string{}
+	for _, p := range plans {
+		if p.Status.RemovedFromBrokerCatalog {
+			continue
+		}
+		plansByClass[p.Spec.ClusterServiceClassRef.Name] = append(plansByClass[p.Spec.ClusterServiceClassRef.Name], p.Spec.ExternalName)
+	}
+
+	for _, svc := range services {
+		entry := DiffEntry{Name: svc.Name, ID: svc.ID, InBroker: true}
+		brokerPlans := []string{}
+		for _, p := range svc.Plans {
+			brokerPlans = append(brokerPlans, p.Name)
+		}
+
+		spec := matchSpec(svc.Name, specs, matchedSpecs)
+		if spec != nil {
+			matchedSpecs[spec] = true
+			entry.InCache = true
+			cachePlans := []string{}
+			for _, p := range spec.Plans {
+				cachePlans = append(cachePlans, p.Name)
+			}
+			entry.Problems = append(entry.Problems, diffPlans(cachePlans, "registry cache", brokerPlans, "broker catalog")...)
+			if v := metadataVersion(svc.Metadata); v != "" && v != spec.Version {
+				entry.Problems = append(entry.Problems, fmt.Sprintf("version [%v] in registry cache, [%v] in broker catalog", spec.Version, v))
+			}
+		}
+
+		class, ok := classesByID[svc.ID]
+		if !ok {
+			entry.Problems = append(entry.Problems, "missing from Service Catalog")
+			entries = append(entries, entry)
+			continue
+		}
+		matchedClasses[class.Name] = true
+		entry.InServiceCatalog = true
+		if class.Status.RemovedFromBrokerCatalog {
+			entry.Problems = append(entry.Problems, "Service Catalog class is marked as removed from broker catalog")
+		}
+		entry.Problems = append(entry.Problems, diffPlans(brokerPlans, "broker catalog", plansByClass[class.Name], "Service Catalog")...)
+		if v := metadataVersion(class.Spec.ExternalMetadata); v != "" && v != metadataVersion(svc.Metadata) {
+			entry.Problems = append(entry.Problems, fmt.Sprintf("version [%v] in broker catalog, [%v] in Service Catalog", metadataVersion(svc.Metadata), v))
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, c := range classes {
+		if matchedClasses[c.Name] {
+			continue
+		}
+		entry := DiffEntry{Name: c.Spec.ExternalName, ID: c.Spec.ExternalID, InServiceCatalog: true}
+		if c.Status.RemovedFromBrokerCatalog {
+			entry.Problems = append(entry.Problems, "stale in Service Catalog, marked as removed from broker catalog")
+		} else {
+			entry.Problems = append(entry.Problems, "stale in Service Catalog, missing from broker catalog")
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, s := range specs {
+		if matchedSpecs[s] {
+			continue
+		}
+		entries = append(entries, DiffEntry{
+			Name:     s.FQName,
+			ID:       s.ID,
+			InCache:  true,
+			Problems: []string{"missing from broker catalog"},
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Find the first unmatched spec whose FQName matches a broker service name, with or without registry prefix
+func matchSpec(serviceName string, specs []*bundle.Spec, matched map[*bundle.Spec]bool) *bundle.Spec {
+	var prefixMatch *bundle.Spec
+	for _, s := range specs {
+		if matched[s] {
+			continue
+		}
+		if s.FQName == serviceName {
+			return s
+		}
+		if prefixMatch == nil && strings.HasSuffix(serviceName, "-"+s.FQName) {
+			prefixMatch = s
+		}
+	}
+	return prefixMatch
+}
+
+// Report plans that are present in only one of two layers
+func diffPlans(upstream []string, upstreamName string, downstream []string, downstreamName string) []string {
+	problems := []string{}
+	for _, p := range upstream {
+		if !contains(downstream, p) {
+			problems = append(problems, fmt.Sprintf("plan [%v] missing from %v", p, downstreamName))
+		}
+	}
+	for _, p := range downstream {
+		if !contains(upstream, p) {
+			problems = append(problems, fmt.Sprintf("plan [%v] not in %v", p, upstreamName))
+		}
+	}
+	return problems
+}
+
+// Version reported in service metadata, empty when the broker doesn't publish one
+func metadataVersion(metadata map[string]interface{}) string {
+	if v, ok := metadata["version"]; ok {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+func contains(s []string, t string) bool {
+	for _, str := range s {
+		if str == t {
+			return true
+		}
+	}
+	return false
+}
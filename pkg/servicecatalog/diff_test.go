@@ -0,0 +1,99 @@
+package servicecatalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestDiffCatalogs(t *testing.T) {
+	specs := []*bundle.Spec{
+		{FQName: "postgresql-apb", Plans: []bundle.Plan{{Name: "dev"}, {Name: "prod"}}},
+		{FQName: "mediawiki-apb", Plans: []bundle.Plan{{Name: "default"}}},
+		{FQName: "new-apb", Plans: []bundle.Plan{{Name: "default"}}},
+	}
+	services := []osb.Service{
+		{ID: "pg-id", Name: "dh-postgresql-apb", Plans: []osb.Plan{{Name: "dev"}}},
+		{ID: "mw-id", Name: "mediawiki-apb", Plans: []osb.Plan{{Name: "default"}}},
+	}
+	classes := []ClusterServiceClass{
+		{Spec: ClusterServiceClassSpec{ExternalName: "dh-postgresql-apb", ExternalID: "pg-id"}},
+		{Spec: ClusterServiceClassSpec{ExternalName: "old-apb", ExternalID: "old-id"}},
+	}
+	classes[0].Name = "pg-id"
+	classes[1].Name = "old-id"
+	plans := []ClusterServicePlan{
+		{Spec: ClusterServicePlanSpec{ExternalName: "dev", ClusterServiceClassRef: ObjectReference{Name: "pg-id"}}},
+	}
+
+	// test case table
+	testCases := []struct {
+		name             string
+		entryName        string
+		inCache          bool
+		inBroker         bool
+		inServiceCatalog bool
+		problem          string
+	}{
+		{
+			name:             "test prefixed broker name matches cache",
+			entryName:        "dh-postgresql-apb",
+			inCache:          true,
+			inBroker:         true,
+			inServiceCatalog: true,
+			problem:          "plan [prod] missing from broker catalog",
+		},
+		{
+			name:             "test missing from service catalog",
+			entryName:        "mediawiki-apb",
+			inCache:          true,
+			inBroker:         true,
+			inServiceCatalog: false,
+			problem:          "missing from Service Catalog",
+		},
+		{
+			name:             "test stale in service catalog",
+			entryName:        "old-apb",
+			inCache:          false,
+			inBroker:         false,
+			inServiceCatalog: true,
+			problem:          "stale in Service Catalog",
+		},
+		{
+			name:             "test missing from broker catalog",
+			entryName:        "new-apb",
+			inCache:          true,
+			inBroker:         false,
+			inServiceCatalog: false,
+			problem:          "missing from broker catalog",
+		},
+	}
+	entries := DiffCatalogs(specs, services, classes, plans)
+	if len(entries) != len(testCases) {
+		t.Fatalf("expected [%v] entries, got [%v]", len(testCases), len(entries))
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var entry *DiffEntry
+			for i := range entries {
+				if entries[i].Name == tc.entryName {
+					entry = &entries[i]
+				}
+			}
+			if entry == nil {
+				t.Fatalf("did not find entry [%v]", tc.entryName)
+				return
+			}
+			if entry.InCache != tc.inCache || entry.InBroker != tc.inBroker || entry.InServiceCatalog != tc.inServiceCatalog {
+				t.Fatalf("unexpected layers for [%v]: cache [%v], broker [%v], service catalog [%v]", tc.entryName, entry.InCache, entry.InBroker, entry.InServiceCatalog)
+				return
+			}
+			if !strings.Contains(strings.Join(entry.Problems, "; "), tc.problem) {
+				t.Fatalf("expected problem [%v], got %v", tc.problem, entry.Problems)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package servicecatalog
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// APIPath is the base path of the Service Catalog API
+const APIPath = "/apis/servicecatalog.k8s.io/v1beta1"
+
+// ListClusterServiceClasses returns every ClusterServiceClass, optionally filtered by owning broker
+func ListClusterServiceClasses(brokerName string) ([]ClusterServiceClass, error) {
+	list := ClusterServiceClassList{}
+	err := getResource(fmt.Sprintf("%v/clusterserviceclasses", APIPath), &list)
+	if err != nil {
+		return nil, err
+	}
+	if brokerName == "" {
+		return list.Items, nil
+	}
+	classes := []ClusterServiceClass{}
+	for _, c := range list.Items {
+		if c.Spec.ClusterServiceBrokerName == brokerName {
+			classes = append(classes, c)
+		}
+	}
+	return classes, nil
+}
+
+// ListClusterServicePlans returns every ClusterServicePlan, optionally filtered by owning broker
+func ListClusterServicePlans(brokerName string) ([]ClusterServicePlan, error) {
+	list := ClusterServicePlanList{}
+	err := getResource(fmt.Sprintf("%v/clusterserviceplans", APIPath), &list)
+	if err != nil {
+		return nil, err
+	}
+	if brokerName == "" {
+		return list.Items, nil
+	}
+	plans := []ClusterServicePlan{}
+	for _, p := range list.Items {
+		if p.Spec.ClusterServiceBrokerName == brokerName {
+			plans = append(plans, p)
+		}
+	}
+	return plans, nil
+}
+
+// GetClusterServiceClass returns a ClusterServiceClass by object name or external name
+func GetClusterServiceClass(name string) (*ClusterServiceClass, error) {
+	class := &ClusterServiceClass{}
+	err := getResource(fmt.Sprintf("%v/clusterserviceclasses/%v", APIPath, name), class)
+	if err == nil {
+		return class, nil
+	}
+	if !kapierrors.IsNotFound(err) {
+		return nil, err
+	}
+	classes, err := ListClusterServiceClasses("")
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range classes {
+		if c.Spec.ExternalName == name {
+			return &classes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("clusterserviceclass [%v] not found", name)
+}
+
+// GetClusterServicePlan returns a ClusterServicePlan by object name or external name
+func GetClusterServicePlan(name string) (*ClusterServicePlan, error) {
+	plan := &ClusterServicePlan{}
+	err := getResource(fmt.Sprintf("%v/clusterserviceplans/%v", APIPath, name), plan)
+	if err == nil {
+		return plan, nil
+	}
+	if !kapierrors.IsNotFound(err) {
+		return nil, err
+	}
+	plans, err := ListClusterServicePlans("")
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range plans {
+		if p.Spec.ExternalName == name {
+			return &plans[i], nil
+		}
+	}
+	return nil, fmt.Errorf("clusterserviceplan [%v] not found", name)
+}
+
+// ListServiceInstances returns the ServiceInstances in a namespace, or in all namespaces when namespace is empty
+func ListServiceInstances(namespace string) ([]ServiceInstance, error) {
+	list := ServiceInstanceList{}
+	err := getResource(namespacedPath(namespace, "serviceinstances"), &list)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// GetServiceInstance returns a ServiceInstance by name
+func GetServiceInstance(namespace string, name string) (*ServiceInstance, error) {
+	instance := &ServiceInstance{}
+	err := getResource(fmt.Sprintf("%v/%v", namespacedPath(namespace, "serviceinstances"), name), instance)
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// ListServiceBindings returns the ServiceBindings in a namespace, or in all namespaces when namespace is empty
+func ListServiceBindings(namespace string) ([]ServiceBinding, error) {
+	list := ServiceBindingList{}
+	err := getResource(namespacedPath(namespace, "servicebindings"), &list)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// GetServiceBinding returns a ServiceBinding by name
+func GetServiceBinding(namespace string, name string) (*ServiceBinding, error) {
+	binding := &ServiceBinding{}
+	err := getResource(fmt.Sprintf("%v/%v", namespacedPath(namespace, "servicebindings"), name), binding)
+	if err != nil {
+		return nil, err
+	}
+	return binding, nil
+}
+
+// GetCondition returns the condition of the given type, or nil if it isn't set
+func GetCondition(conditions []Condition, conditionType string) *Condition {
+	for i, c := range conditions {
+		if c.Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Build the API path of a namespaced resource, spanning all namespaces when namespace is empty
+func namespacedPath(namespace string, resource string) string {
+	if namespace == "" {
+		return fmt.Sprintf("%v/%v", APIPath, resource)
+	}
+	return fmt.Sprintf("%v/namespaces/%v/%v", APIPath, namespace, resource)
+}
+
+// GET a Service Catalog resource and unmarshal it into obj
+func getResource(path string, obj interface{}) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	body, err := k8scli.Client.CoreV1().RESTClient().Get().AbsPath(path).Do().Raw()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, obj)
+}
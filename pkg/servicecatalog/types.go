@@ -0,0 +1,193 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package servicecatalog
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The types below mirror the subset of the servicecatalog.k8s.io/v1beta1 API used by the apb tool
+
+// ClusterServiceClass is a service offered by a cluster-scoped broker
+type ClusterServiceClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ClusterServiceClassSpec `json:"spec"`
+	Status            ServiceClassStatus      `json:"status"`
+}
+
+// ClusterServiceClassList is a list of ClusterServiceClasses
+type ClusterServiceClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterServiceClass `json:"items"`
+}
+
+// ClusterServiceClassSpec describes a ClusterServiceClass as reported by its broker
+type ClusterServiceClassSpec struct {
+	ClusterServiceBrokerName string                 `json:"clusterServiceBrokerName"`
+	ExternalName             string                 `json:"externalName"`
+	ExternalID               string                 `json:"externalID"`
+	Description              string                 `json:"description"`
+	Bindable                 bool                   `json:"bindable"`
+	PlanUpdatable            bool                   `json:"planUpdatable"`
+	ExternalMetadata         map[string]interface{} `json:"externalMetadata,omitempty"`
+	Tags                     []string               `json:"tags,omitempty"`
+}
+
+// ServiceClassStatus is the status of a service class
+type ServiceClassStatus struct {
+	RemovedFromBrokerCatalog bool `json:"removedFromBrokerCatalog"`
+}
+
+// ClusterServicePlan is a plan of a ClusterServiceClass
+type ClusterServicePlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ClusterServicePlanSpec `json:"spec"`
+	Status            ServicePlanStatus      `json:"status"`
+}
+
+// ClusterServicePlanList is a list of ClusterServicePlans
+type ClusterServicePlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterServicePlan `json:"items"`
+}
+
+// ClusterServicePlanSpec describes a ClusterServicePlan as reported by its broker
+type ClusterServicePlanSpec struct {
+	ClusterServiceBrokerName string                 `json:"clusterServiceBrokerName"`
+	ExternalName             string                 `json:"externalName"`
+	ExternalID               string                 `json:"externalID"`
+	Description              string                 `json:"description"`
+	Free                     bool                   `json:"free"`
+	Bindable                 *bool                  `json:"bindable,omitempty"`
+	ExternalMetadata         map[string]interface{} `json:"externalMetadata,omitempty"`
+	ClusterServiceClassRef   ObjectReference        `json:"clusterServiceClassRef"`
+}
+
+// ServicePlanStatus is the status of a service plan
+type ServicePlanStatus struct {
+	RemovedFromBrokerCatalog bool `json:"removedFromBrokerCatalog"`
+}
+
+// ObjectReference references another Service Catalog object by name
+type ObjectReference struct {
+	Name string `json:"name"`
+}
+
+// ServiceInstance is a provisioned instance of a service class
+type ServiceInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ServiceInstanceSpec   `json:"spec"`
+	Status            ServiceInstanceStatus `json:"status"`
+}
+
+// ServiceInstanceList is a list of ServiceInstances
+type ServiceInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServiceInstance `json:"items"`
+}
+
+// ServiceInstanceSpec is the requested class and plan of a ServiceInstance
+type ServiceInstanceSpec struct {
+	ClusterServiceClassExternalName string           `json:"clusterServiceClassExternalName,omitempty"`
+	ClusterServicePlanExternalName  string           `json:"clusterServicePlanExternalName,omitempty"`
+	ClusterServiceClassRef          *ObjectReference `json:"clusterServiceClassRef,omitempty"`
+	ClusterServicePlanRef           *ObjectReference `json:"clusterServicePlanRef,omitempty"`
+	ExternalID                      string           `json:"externalID"`
+}
+
+// ServiceInstanceStatus is the provisioning state of a ServiceInstance
+type ServiceInstanceStatus struct {
+	Conditions         []Condition `json:"conditions"`
+	AsyncOpInProgress  bool        `json:"asyncOpInProgress"`
+	LastOperation      *string     `json:"lastOperation,omitempty"`
+	DashboardURL       *string     `json:"dashboardURL,omitempty"`
+	CurrentOperation   string      `json:"currentOperation,omitempty"`
+	LastConditionState string      `json:"lastConditionState,omitempty"`
+	ProvisionStatus    string      `json:"provisionStatus,omitempty"`
+	DeprovisionStatus  string      `json:"deprovisionStatus,omitempty"`
+}
+
+// ServiceBinding is a set of credentials for a ServiceInstance
+type ServiceBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ServiceBindingSpec   `json:"spec"`
+	Status            ServiceBindingStatus `json:"status"`
+}
+
+// ServiceBindingList is a list of ServiceBindings
+type ServiceBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServiceBinding `json:"items"`
+}
+
+// ServiceBindingSpec references the bound instance and the secret holding its credentials
+type ServiceBindingSpec struct {
+	InstanceRef ObjectReference `json:"instanceRef"`
+	SecretName  string          `json:"secretName,omitempty"`
+	ExternalID  string          `json:"externalID"`
+}
+
+// ServiceBindingStatus is the binding state of a ServiceBinding
+type ServiceBindingStatus struct {
+	Conditions         []Condition `json:"conditions"`
+	AsyncOpInProgress  bool        `json:"asyncOpInProgress"`
+	LastOperation      *string     `json:"lastOperation,omitempty"`
+	CurrentOperation   string      `json:"currentOperation,omitempty"`
+	LastConditionState string      `json:"lastConditionState,omitempty"`
+	UnbindStatus       string      `json:"unbindStatus,omitempty"`
+}
+
+// Condition is a status condition of a Service Catalog object
+type Condition struct {
+	Type               string      `json:"type"`
+	Status             string      `json:"status"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	Reason             string      `json:"reason"`
+	Message            string      `json:"message"`
+}
+
+// ServiceBroker is a broker registered with the Service Catalog. Cluster-scoped ClusterServiceBrokers
+// and namespaced ServiceBrokers share the same spec and status.
+type ServiceBroker struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ServiceBrokerSpec   `json:"spec"`
+	Status            ServiceBrokerStatus `json:"status"`
+}
+
+// ServiceBrokerSpec describes where the broker lives and how its catalog is relisted
+type ServiceBrokerSpec struct {
+	URL            string `json:"url"`
+	RelistBehavior string `json:"relistBehavior,omitempty"`
+	RelistRequests int64  `json:"relistRequests"`
+}
+
+// ServiceBrokerStatus is the catalog retrieval state of a broker
+type ServiceBrokerStatus struct {
+	Conditions               []Condition  `json:"conditions"`
+	ReconciledGeneration     int64        `json:"reconciledGeneration"`
+	OperationStartTime       *metav1.Time `json:"operationStartTime,omitempty"`
+	LastCatalogRetrievalTime *metav1.Time `json:"lastCatalogRetrievalTime,omitempty"`
+}
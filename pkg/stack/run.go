@@ -0,0 +1,321 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stack
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/automationbroker/apb/pkg/binding"
+	"github.com/automationbroker/apb/pkg/runner"
+	"k8s.io/api/core/v1"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Options for bringing a stack up or down
+type Options struct {
+	SandboxRole string
+	// Run holds the pod, environment and extra-vars options of every APB. Its plan, parameters
+	// and instance ID are set per APB.
+	Run runner.RunOptions
+	// Recorded is called for every instance provisioned or deprovisioned, to keep the local
+	// list of instances up to date
+	Recorded func(action string, entry Entry, instanceID string)
+}
+
+// action is an APB pod started for an entry of the stack
+type action struct {
+	entry   Entry
+	podName string
+}
+
+// Up provisions the APBs of a stack in dependency order. Each APB starts as soon as the APBs it
+// depends on are provisioned, so independent APBs run in parallel. APBs already provisioned by an
+// earlier run are kept.
+func Up(s *Stack, opts Options) error {
+	// Reject unknown and cyclic dependencies before starting anything
+	_, err := s.Levels()
+	if err != nil {
+		return err
+	}
+	// Provision pod of every APB, which is also the name of its credentials secret
+	provisioned := map[string]string{}
+	start := func(e Entry) ([]action, error) {
+		instances, err := runner.ListInstances(e.Namespace, s.selector(e))
+		if err != nil {
+			return nil, err
+		}
+		if podName, running := existingProvision(instances); podName != "" {
+			if running {
+				fmt.Printf("APB [%v] of stack [%v] is still being provisioned by pod [%v]\n", e.Name, s.Name, podName)
+				return []action{{entry: e, podName: podName}}, nil
+			}
+			fmt.Printf("APB [%v] of stack [%v] is already provisioned by pod [%v]\n", e.Name, s.Name, podName)
+			provisioned[e.Name] = podName
+			return nil, nil
+		}
+
+		params, err := e.params()
+		if err != nil {
+			return nil, err
+		}
+		creds, err := upstreamCredentials(s, e, provisioned)
+		if err != nil {
+			return nil, err
+		}
+		bound, err := e.bindParams(creds)
+		if err != nil {
+			return nil, err
+		}
+		runOpts := s.runOptions(e, opts.Run, append(params, bound...), "")
+		runOpts.SensitiveParams = e.boundParams()
+		fmt.Printf("Provisioning APB [%v] of stack [%v]\n", e.Name, s.Name)
+		podName, err := runner.RunBundle("provision", e.Namespace, e.Bundle, opts.SandboxRole, e.Registry, false, true, runOpts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to provision APB [%v]: %v", e.Name, err)
+		}
+		if opts.Recorded != nil {
+			opts.Recorded("provision", e, instanceID("provision", podName))
+		}
+		return []action{{entry: e, podName: podName}}, nil
+	}
+	finished := func(e Entry, actions []action) {
+		for _, a := range actions {
+			provisioned[a.entry.Name] = a.podName
+		}
+	}
+	err = s.schedule("provision", func(e Entry) []string { return e.dependencies() }, start, s.waiter("provision"), finished)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stack [%v] is up\n", s.Name)
+	return nil
+}
+
+// Down deprovisions every instance of the APBs of a stack, in reverse dependency order. Each APB
+// starts as soon as the APBs depending on it are deprovisioned.
+func Down(s *Stack, opts Options) error {
+	// Reject unknown and cyclic dependencies before starting anything
+	_, err := s.Levels()
+	if err != nil {
+		return err
+	}
+	start := func(e Entry) ([]action, error) {
+		instances, err := runner.ListInstances(e.Namespace, s.selector(e))
+		if err != nil {
+			return nil, err
+		}
+		if len(instances) == 0 {
+			fmt.Printf("APB [%v] of stack [%v] has no instances\n", e.Name, s.Name)
+			return nil, nil
+		}
+		params, err := e.params()
+		if err != nil {
+			return nil, err
+		}
+		started := []action{}
+		for _, instance := range instances {
+			fmt.Printf("Deprovisioning instance [%v] of APB [%v] of stack [%v]\n", instance.ID, e.Name, s.Name)
+			podName, err := runner.RunBundle("deprovision", e.Namespace, e.Bundle, opts.SandboxRole, e.Registry, false, true, s.runOptions(e, opts.Run, params, instance.ID))
+			if err != nil {
+				return nil, fmt.Errorf("unable to deprovision APB [%v]: %v", e.Name, err)
+			}
+			if opts.Recorded != nil {
+				opts.Recorded("deprovision", e, instance.ID)
+			}
+			started = append(started, action{entry: e, podName: podName})
+		}
+		return started, nil
+	}
+	err = s.schedule("deprovision", s.dependents, start, s.waiter("deprovision"), func(Entry, []action) {})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stack [%v] is down\n", s.Name)
+	return nil
+}
+
+// schedule starts each APB once the APBs returned by waitsFor finished, and waits for the pods of
+// the started APBs in parallel. start returns no actions when there is nothing to wait for. After a
+// failure, no more APBs are started and the running ones are waited for.
+func (s *Stack) schedule(actionName string, waitsFor func(Entry) []string, start func(Entry) ([]action, error),
+	wait func([]action) error, finished func(Entry, []action)) error {
+	type outcome struct {
+		entry   Entry
+		actions []action
+		err     error
+	}
+	outcomes := make(chan outcome)
+	started := map[string]bool{}
+	done := map[string]bool{}
+	failed := []string{}
+	running := 0
+	var startErr error
+	for {
+		// Finishing an APB without actions may unblock APBs earlier in the manifest
+		for progress := true; progress && startErr == nil && len(failed) == 0; {
+			progress = false
+			for _, e := range s.APBs {
+				if started[e.Name] || !allDone(waitsFor(e), done) {
+					continue
+				}
+				started[e.Name] = true
+				actions, err := start(e)
+				if err != nil {
+					startErr = err
+					break
+				}
+				if len(actions) == 0 {
+					done[e.Name] = true
+					finished(e, nil)
+					progress = true
+					continue
+				}
+				running++
+				go func(e Entry, actions []action) {
+					outcomes <- outcome{entry: e, actions: actions, err: wait(actions)}
+				}(e, actions)
+			}
+		}
+		if running == 0 {
+			break
+		}
+		o := <-outcomes
+		running--
+		if o.err != nil {
+			failed = append(failed, o.entry.Name)
+			continue
+		}
+		done[o.entry.Name] = true
+		finished(o.entry, o.actions)
+	}
+	if startErr != nil {
+		return startErr
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%v failed for APBs: %v. Check the logs with 'apb bundle logs <pod-name>'", actionName, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// waiter waits for the pods of an APB of the stack
+func (s *Stack) waiter(actionName string) func([]action) error {
+	return func(actions []action) error {
+		return waitForActions(s, actionName, actions)
+	}
+}
+
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// runOptions returns the run options of an APB of the stack, labelling its pod with the stack
+func (s *Stack) runOptions(e Entry, base runner.RunOptions, params []string, instanceID string) runner.RunOptions {
+	opts := base
+	opts.Plan = e.Plan
+	opts.Params = params
+	opts.InstanceID = instanceID
+	opts.Pod.Labels = append(append([]string{}, base.Pod.Labels...),
+		fmt.Sprintf("%v=%v", StackLabel, s.Name),
+		fmt.Sprintf("%v=%v", StackEntryLabel, e.Name))
+	return opts
+}
+
+// existingProvision returns the provision pod of an instance that succeeded or is still running
+func existingProvision(instances []runner.Instance) (podName string, running bool) {
+	for _, instance := range instances {
+		switch v1.PodPhase(instance.Phase) {
+		case v1.PodSucceeded:
+			return fmt.Sprintf("bundle-provision-%v", instance.ID), false
+		case v1.PodPending, v1.PodRunning:
+			podName = fmt.Sprintf("bundle-provision-%v", instance.ID)
+			running = true
+		}
+	}
+	return podName, running
+}
+
+// upstreamCredentials reads the credentials of the APBs an entry binds to
+func upstreamCredentials(s *Stack, e Entry, provisioned map[string]string) (map[string]map[string]interface{}, error) {
+	creds := map[string]map[string]interface{}{}
+	for _, b := range e.Bind {
+		if _, ok := creds[b.From]; ok {
+			continue
+		}
+		upstream := s.entry(b.From)
+		c, err := binding.ExtractCredentials(provisioned[b.From], upstream.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read credentials of APB [%v]: %v", b.From, err)
+		}
+		creds[b.From] = c
+	}
+	return creds, nil
+}
+
+func (s *Stack) entry(name string) Entry {
+	for _, e := range s.APBs {
+		if e.Name == name {
+			return e
+		}
+	}
+	return Entry{}
+}
+
+// waitForActions waits for APB pods running in parallel, and fails if any of them failed
+func waitForActions(s *Stack, actionName string, actions []action) error {
+	errs := make([]error, len(actions))
+	results := make([]runner.Result, len(actions))
+	var wg sync.WaitGroup
+	for i, a := range actions {
+		wg.Add(1)
+		go func(i int, a action) {
+			defer wg.Done()
+			results[i], errs[i] = runner.WaitForBundle(a.podName, a.entry.Namespace, false)
+		}(i, a)
+	}
+	wg.Wait()
+
+	failed := []string{}
+	for i, a := range actions {
+		if errs[i] != nil {
+			log.Errorf("APB [%v] of stack [%v] failed to %v: %v", a.entry.Name, s.Name, actionName, errs[i])
+			failed = append(failed, a.entry.Name)
+			continue
+		}
+		fmt.Printf("APB [%v] of stack [%v] finished %v", a.entry.Name, s.Name, actionName)
+		if results[i].Description != "" {
+			fmt.Printf(": %v", results[i].Description)
+		}
+		fmt.Println()
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%v failed for APBs: %v", actionName, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// instanceID returns the instance ID from the name of an APB pod
+func instanceID(action string, podName string) string {
+	return strings.TrimPrefix(podName, fmt.Sprintf("bundle-%v-", action))
+}
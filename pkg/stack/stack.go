@@ -0,0 +1,266 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package stack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/automationbroker/apb/pkg/binding"
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels set on the APB pods of a stack, so that 'stack down' finds its instances
+const (
+	StackLabel      = "apb.automationbroker.io/stack"
+	StackEntryLabel = "apb.automationbroker.io/stack-apb"
+)
+
+// Stack declares APBs that are provisioned and deprovisioned together
+type Stack struct {
+	// Name identifies the instances of the stack in the cluster
+	Name string `json:"name"`
+	// Namespace is the default namespace of the APBs
+	Namespace string  `json:"namespace,omitempty"`
+	APBs      []Entry `json:"apbs"`
+}
+
+// Entry is an APB of a stack
+type Entry struct {
+	// Name identifies the APB within the stack
+	Name      string `json:"name"`
+	Bundle    string `json:"bundle"`
+	Plan      string `json:"plan,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Registry  string `json:"registry,omitempty"`
+	// Parameters are given like --param, so values can refer to secrets, files and environment variables
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// DependsOn names the APBs provisioned before this one
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Bind passes credentials of other APBs as parameters. It implies a dependency on them.
+	Bind []Bind `json:"bind,omitempty"`
+}
+
+// Bind passes credentials extracted by an upstream APB as parameters
+type Bind struct {
+	// From names the APB whose credentials are used
+	From string `json:"from"`
+	// Parameters maps parameter names to credential names
+	Parameters map[string]string `json:"parameters"`
+}
+
+// Load reads a stack manifest. APBs without a namespace default to the stack namespace, and then
+// to defaultNamespace.
+func Load(path string, defaultNamespace string) (*Stack, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stack{}
+	err = yaml.Unmarshal(data, s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse stack [%v]: %v", path, err)
+	}
+	if s.Namespace == "" {
+		s.Namespace = defaultNamespace
+	}
+	for i := range s.APBs {
+		if s.APBs[i].Namespace == "" {
+			s.APBs[i].Namespace = s.Namespace
+		}
+	}
+	err = s.validate()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Stack) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("stack has no name")
+	}
+	if errs := validation.IsValidLabelValue(s.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid stack name [%v]: %v", s.Name, strings.Join(errs, ", "))
+	}
+	if len(s.APBs) == 0 {
+		return fmt.Errorf("stack [%v] has no APBs", s.Name)
+	}
+	names := map[string]bool{}
+	for _, e := range s.APBs {
+		if e.Name == "" {
+			return fmt.Errorf("APB [%v] has no name", e.Bundle)
+		}
+		if errs := validation.IsValidLabelValue(e.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid APB name [%v]: %v", e.Name, strings.Join(errs, ", "))
+		}
+		if names[e.Name] {
+			return fmt.Errorf("APB name [%v] is used more than once", e.Name)
+		}
+		names[e.Name] = true
+		if e.Bundle == "" {
+			return fmt.Errorf("APB [%v] has no bundle", e.Name)
+		}
+		if e.Namespace == "" {
+			return fmt.Errorf("APB [%v] has no namespace", e.Name)
+		}
+	}
+	for _, e := range s.APBs {
+		for _, dep := range e.dependencies() {
+			if !names[dep] {
+				return fmt.Errorf("APB [%v] depends on unknown APB [%v]", e.Name, dep)
+			}
+			if dep == e.Name {
+				return fmt.Errorf("APB [%v] depends on itself", e.Name)
+			}
+		}
+		bound := map[string]bool{}
+		for _, b := range e.Bind {
+			for param := range b.Parameters {
+				if _, ok := e.Parameters[param]; ok || bound[param] {
+					return fmt.Errorf("parameter [%v] of APB [%v] is set more than once", param, e.Name)
+				}
+				bound[param] = true
+			}
+		}
+	}
+	_, err := s.Levels()
+	return err
+}
+
+// dependencies are the APBs declared in DependsOn and Bind
+func (e Entry) dependencies() []string {
+	deps := append([]string{}, e.DependsOn...)
+	for _, b := range e.Bind {
+		deps = append(deps, b.From)
+	}
+	return deps
+}
+
+// dependents are the APBs depending on an APB
+func (s *Stack) dependents(e Entry) []string {
+	names := []string{}
+	for _, other := range s.APBs {
+		for _, dep := range other.dependencies() {
+			if dep == e.Name {
+				names = append(names, other.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Levels orders the APBs so that each level only depends on earlier ones. APBs within a level are
+// independent and keep their manifest order.
+func (s *Stack) Levels() ([][]Entry, error) {
+	done := map[string]bool{}
+	remaining := append([]Entry{}, s.APBs...)
+	levels := [][]Entry{}
+	for len(remaining) > 0 {
+		level := []Entry{}
+		next := []Entry{}
+		for _, e := range remaining {
+			ready := true
+			for _, dep := range e.dependencies() {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, e)
+			} else {
+				next = append(next, e)
+			}
+		}
+		if len(level) == 0 {
+			cycle := []string{}
+			for _, e := range next {
+				cycle = append(cycle, e.Name)
+			}
+			return nil, fmt.Errorf("dependency cycle between APBs: %v", strings.Join(cycle, ", "))
+		}
+		for _, e := range level {
+			done[e.Name] = true
+		}
+		levels = append(levels, level)
+		remaining = next
+	}
+	return levels, nil
+}
+
+// selector matches the APB pods of an entry
+func (s *Stack) selector(e Entry) string {
+	return fmt.Sprintf("%v=%v,%v=%v", StackLabel, s.Name, StackEntryLabel, e.Name)
+}
+
+// params returns the declared parameters as name=value, sorted by name. Strings are passed as they
+// are, other values as JSON.
+func (e Entry) params() ([]string, error) {
+	params := []string{}
+	for name, value := range e.Parameters {
+		s, ok := value.(string)
+		if !ok {
+			d, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode parameter [%v] of APB [%v]: %v", name, e.Name, err)
+			}
+			s = string(d)
+		}
+		params = append(params, fmt.Sprintf("%v=%v", name, s))
+	}
+	sort.Strings(params)
+	return params, nil
+}
+
+// boundParams returns the names of the parameters set from upstream credentials, sorted by name
+func (e Entry) boundParams() []string {
+	names := []string{}
+	for _, b := range e.Bind {
+		for param := range b.Parameters {
+			names = append(names, param)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bindParams returns the parameters set from upstream credentials as name=value, sorted by name.
+// creds holds the credentials of each upstream APB.
+func (e Entry) bindParams(creds map[string]map[string]interface{}) ([]string, error) {
+	params := []string{}
+	for _, b := range e.Bind {
+		for param, credential := range b.Parameters {
+			value, ok := creds[b.From][credential]
+			if !ok {
+				return nil, fmt.Errorf("APB [%v] has no credential [%v] for parameter [%v] of APB [%v]", b.From, credential, param, e.Name)
+			}
+			d, err := binding.FormatCredential(value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode credential [%v] of APB [%v]: %v", credential, b.From, err)
+			}
+			params = append(params, fmt.Sprintf("%v=%v", param, string(d)))
+		}
+	}
+	sort.Strings(params)
+	return params, nil
+}
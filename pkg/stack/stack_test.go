@@ -0,0 +1,319 @@
+package stack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automationbroker/apb/pkg/runner"
+)
+
+const testStack = `
+name: shop
+namespace: shop-dev
+apbs:
+- name: db
+  bundle: postgresql-apb
+  plan: dev
+  parameters:
+    postgresql_user: admin
+    postgresql_version: 9.6
+- name: cache
+  bundle: redis-apb
+  namespace: shop-cache
+- name: app
+  bundle: shop-apb
+  dependsOn: [cache]
+  parameters:
+    replicas: 2
+    features: [cart, search]
+  bind:
+  - from: db
+    parameters:
+      db_host: DB_HOST
+      db_port: DB_PORT
+`
+
+func writeStack(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "stack")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "stack.yml")
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("unable to write stack: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeStack(t, testStack)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	s, err := Load(path, "current")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespaces := map[string]string{}
+	for _, e := range s.APBs {
+		namespaces[e.Name] = e.Namespace
+	}
+	expected := map[string]string{"db": "shop-dev", "cache": "shop-cache", "app": "shop-dev"}
+	if !reflect.DeepEqual(namespaces, expected) {
+		t.Fatalf("expected namespaces %v, got %v", expected, namespaces)
+	}
+
+	levels, err := s.Levels()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := [][]string{}
+	for _, level := range levels {
+		l := []string{}
+		for _, e := range level {
+			l = append(l, e.Name)
+		}
+		names = append(names, l)
+	}
+	if !reflect.DeepEqual(names, [][]string{{"db", "cache"}, {"app"}}) {
+		t.Fatalf("unexpected levels %v", names)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name  string
+		stack Stack
+	}{
+		{
+			name:  "test missing name",
+			stack: Stack{APBs: []Entry{{Name: "db", Bundle: "postgresql-apb", Namespace: "ns"}}},
+		},
+		{
+			name:  "test no APBs",
+			stack: Stack{Name: "shop"},
+		},
+		{
+			name: "test duplicate APB",
+			stack: Stack{Name: "shop", APBs: []Entry{
+				{Name: "db", Bundle: "postgresql-apb", Namespace: "ns"},
+				{Name: "db", Bundle: "mysql-apb", Namespace: "ns"},
+			}},
+		},
+		{
+			name:  "test missing bundle",
+			stack: Stack{Name: "shop", APBs: []Entry{{Name: "db", Namespace: "ns"}}},
+		},
+		{
+			name:  "test unknown dependency",
+			stack: Stack{Name: "shop", APBs: []Entry{{Name: "db", Bundle: "postgresql-apb", Namespace: "ns", DependsOn: []string{"cache"}}}},
+		},
+		{
+			name: "test cycle",
+			stack: Stack{Name: "shop", APBs: []Entry{
+				{Name: "db", Bundle: "postgresql-apb", Namespace: "ns", DependsOn: []string{"app"}},
+				{Name: "app", Bundle: "shop-apb", Namespace: "ns", Bind: []Bind{{From: "db"}}},
+			}},
+		},
+		{
+			name: "test bound parameter also declared",
+			stack: Stack{Name: "shop", APBs: []Entry{
+				{Name: "db", Bundle: "postgresql-apb", Namespace: "ns"},
+				{Name: "app", Bundle: "shop-apb", Namespace: "ns",
+					Parameters: map[string]interface{}{"db_host": "localhost"},
+					Bind:       []Bind{{From: "db", Parameters: map[string]string{"db_host": "DB_HOST"}}}},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.stack.validate(); err == nil {
+				t.Fatalf("expected validation error")
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	e := Entry{
+		Name: "app",
+		Parameters: map[string]interface{}{
+			"replicas": float64(2),
+			"features": []interface{}{"cart", "search"},
+			"password": "@secret:shop/app/password",
+		},
+		Bind: []Bind{{From: "db", Parameters: map[string]string{"db_host": "DB_HOST", "db_port": "DB_PORT"}}},
+	}
+	params, err := e.params()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{`features=["cart","search"]`, "password=@secret:shop/app/password", "replicas=2"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("expected %v, got %v", expected, params)
+	}
+
+	creds := map[string]map[string]interface{}{"db": {"DB_HOST": "postgresql", "DB_PORT": float64(5432)}}
+	bound, err := e.bindParams(creds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []string{"db_host=postgresql", "db_port=5432"}
+	if !reflect.DeepEqual(bound, expected) {
+		t.Fatalf("expected %v, got %v", expected, bound)
+	}
+
+	_, err = e.bindParams(map[string]map[string]interface{}{"db": {"DB_HOST": "postgresql"}})
+	if err == nil {
+		t.Fatalf("expected error for missing credential")
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	s := &Stack{Name: "shop"}
+	base := runner.RunOptions{}
+	base.Pod.Labels = []string{"team=web"}
+	opts := s.runOptions(Entry{Name: "db", Plan: "dev"}, base, []string{"a=1"}, "1234")
+	expected := []string{"team=web", StackLabel + "=shop", StackEntryLabel + "=db"}
+	if !reflect.DeepEqual(opts.Pod.Labels, expected) {
+		t.Fatalf("expected labels %v, got %v", expected, opts.Pod.Labels)
+	}
+	if len(base.Pod.Labels) != 1 {
+		t.Fatalf("base labels were modified: %v", base.Pod.Labels)
+	}
+	if opts.Plan != "dev" || opts.InstanceID != "1234" || opts.Params[0] != "a=1" {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
+
+func TestExistingProvision(t *testing.T) {
+	// test case table
+	testCases := []struct {
+		name      string
+		instances []runner.Instance
+		podName   string
+		running   bool
+	}{
+		{
+			name: "test none",
+		},
+		{
+			name:      "test failed",
+			instances: []runner.Instance{{ID: "1", Phase: "Failed"}},
+		},
+		{
+			name:      "test running",
+			instances: []runner.Instance{{ID: "1", Phase: "Running"}},
+			podName:   "bundle-provision-1",
+			running:   true,
+		},
+		{
+			name:      "test succeeded wins",
+			instances: []runner.Instance{{ID: "1", Phase: "Running"}, {ID: "2", Phase: "Succeeded"}},
+			podName:   "bundle-provision-2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			podName, running := existingProvision(tc.instances)
+			if podName != tc.podName || running != tc.running {
+				t.Fatalf("expected (%v, %v), got (%v, %v)", tc.podName, tc.running, podName, running)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	s := &Stack{Name: "shop", APBs: []Entry{
+		{Name: "slow"},
+		{Name: "db"},
+		{Name: "app", DependsOn: []string{"db"}},
+	}}
+	release := make(chan struct{})
+	started := []string{}
+	start := func(e Entry) ([]action, error) {
+		started = append(started, e.Name)
+		if e.Name == "app" {
+			close(release)
+		}
+		return []action{{entry: e}}, nil
+	}
+	wait := func(actions []action) error {
+		if actions[0].entry.Name != "slow" {
+			return nil
+		}
+		select {
+		case <-release:
+			return nil
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("app didn't start while slow was running")
+		}
+	}
+	finished := []string{}
+	err := s.schedule("provision", func(e Entry) []string { return e.dependencies() }, start, wait, func(e Entry, _ []action) {
+		finished = append(finished, e.Name)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(started, []string{"slow", "db", "app"}) {
+		t.Fatalf("unexpected start order %v", started)
+	}
+	if len(finished) != 3 {
+		t.Fatalf("expected all APBs to finish, got %v", finished)
+	}
+}
+
+func TestScheduleFailure(t *testing.T) {
+	s := &Stack{Name: "shop", APBs: []Entry{
+		{Name: "db"},
+		{Name: "cache"},
+		{Name: "app", DependsOn: []string{"db"}},
+	}}
+	started := []string{}
+	start := func(e Entry) ([]action, error) {
+		started = append(started, e.Name)
+		if e.Name == "cache" {
+			// Already provisioned
+			return nil, nil
+		}
+		return []action{{entry: e}}, nil
+	}
+	wait := func(actions []action) error {
+		return fmt.Errorf("pod failed")
+	}
+	err := s.schedule("provision", func(e Entry) []string { return e.dependencies() }, start, wait, func(Entry, []action) {})
+	if err == nil || !strings.Contains(err.Error(), "db") {
+		t.Fatalf("expected db to fail, got %v", err)
+	}
+	if !reflect.DeepEqual(started, []string{"db", "cache"}) {
+		t.Fatalf("expected app not to start, got %v", started)
+	}
+}
+
+func TestDependentsAndBoundParams(t *testing.T) {
+	path := writeStack(t, testStack)
+	defer os.RemoveAll(filepath.Dir(path))
+	s, err := Load(path, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := s.dependents(s.APBs[0]); !reflect.DeepEqual(d, []string{"app"}) {
+		t.Fatalf("expected app to depend on db, got %v", d)
+	}
+	if d := s.dependents(s.APBs[2]); len(d) != 0 {
+		t.Fatalf("expected nothing to depend on app, got %v", d)
+	}
+	if b := s.APBs[2].boundParams(); !reflect.DeepEqual(b, []string{"db_host", "db_port"}) {
+		t.Fatalf("unexpected bound parameters %v", b)
+	}
+}
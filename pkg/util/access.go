@@ -0,0 +1,53 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package util
+
+import (
+	"github.com/automationbroker/bundle-lib/clients"
+	authv1 "k8s.io/api/authorization/v1"
+)
+
+// ResourceAccess describes an action on a resource to check with CanI
+type ResourceAccess struct {
+	Namespace string
+	Verb      string
+	Group     string
+	Resource  string
+}
+
+// CanI asks the API server whether the current user may perform an action, like 'oc auth can-i'
+func CanI(access ResourceAccess) (bool, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return false, err
+	}
+	review := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Namespace: access.Namespace,
+				Verb:      access.Verb,
+				Group:     access.Group,
+				Resource:  access.Resource,
+			},
+		},
+	}
+	result, err := k8scli.Client.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+	if err != nil {
+		return false, err
+	}
+	return result.Status.Allowed, nil
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// updateConflictRetries is how many times an update is retried when another client modified the resource first
+const updateConflictRetries = 5
+
+// GetResource returns the API object at resourcePath
+func GetResource(resourcePath string) (*unstructured.Unstructured, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	body, err := k8scli.Client.CoreV1().RESTClient().Get().AbsPath(resourcePath).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	err = obj.UnmarshalJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// ListResources returns the API objects in the collection at resourcePath
+func ListResources(resourcePath string) (*unstructured.UnstructuredList, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	body, err := k8scli.Client.CoreV1().RESTClient().Get().AbsPath(resourcePath).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	err = list.UnmarshalJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateResource reads the API object at resourcePath, applies mutate and writes it back. The write
+// carries the resourceVersion that was read, so a concurrent modification causes a conflict and the
+// read-mutate-write cycle is retried.
+func UpdateResource(resourcePath string, mutate func(obj *unstructured.Unstructured) error) (*unstructured.Unstructured, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	restClient := k8scli.Client.CoreV1().RESTClient()
+
+	for attempt := 1; attempt <= updateConflictRetries; attempt++ {
+		obj, err := GetResource(resourcePath)
+		if err != nil {
+			return nil, err
+		}
+		err = mutate(obj)
+		if err != nil {
+			return nil, err
+		}
+		body, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		result, err := restClient.Put().AbsPath(resourcePath).SetHeader("Content-Type", "application/json").Body(body).Do().Raw()
+		if kapierrors.IsConflict(err) {
+			log.Debugf("Conflict updating [%v] on attempt %v, retrying", resourcePath, attempt)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		updated := &unstructured.Unstructured{}
+		err = updated.UnmarshalJSON(result)
+		if err != nil {
+			return nil, err
+		}
+		return updated, nil
+	}
+	return nil, fmt.Errorf("[%v] was modified concurrently %v times, giving up", resourcePath, updateConflictRetries)
+}